@@ -0,0 +1,94 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Backup streams every key/value in the store to w using Badger's own
+// backup stream format, returning a version that can later be passed as
+// since to capture only what changed after this call.
+func (t *Triplestore) Backup(w io.Writer, since uint64) (uint64, error) {
+	return t.db.Backup(w, since)
+}
+
+// Restore loads a stream previously written by Backup into the store.
+func (t *Triplestore) Restore(r io.Reader) error {
+	return t.db.Load(r)
+}
+
+// subscribePollInterval is how often Subscribe re-checks the watched
+// prefixes for new keys.
+const subscribePollInterval = 200 * time.Millisecond
+
+// Subscribe calls fn once for every key currently present under any of
+// prefixes (e.g. dbSPO, dbPOS, dbSOP) and then again for every key added
+// afterwards, until ctx is done. This Badger build predates its native
+// key-change subscription API, so Subscribe approximates it by polling:
+// it diffs a snapshot of the watched prefixes' keys against the previous
+// poll rather than being pushed individual writes, which costs memory
+// proportional to the size of the watched key space and can coalesce
+// bursts of writes between polls into a single batch of fn calls.
+func (t *Triplestore) Subscribe(ctx context.Context, prefixes [][]byte, fn func(key []byte)) error {
+	seen := map[string]bool{}
+	if err := t.pollPrefixes(prefixes, seen, fn); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(subscribePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := t.pollPrefixes(prefixes, seen, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pollPrefixes scans prefixes, calling fn for every key not already in
+// seen and adding it, then drops from seen every key no longer present,
+// so a repeat poll reports keys added since the previous poll.
+func (t *Triplestore) pollPrefixes(prefixes [][]byte, seen map[string]bool, fn func(key []byte)) error {
+	// These index rows are stored with an empty value (see put/putQuad),
+	// so there's never a value worth prefetching here - only the key.
+	iopts := t.iopts
+	iopts.PrefetchValues = false
+
+	current := map[string]bool{}
+	err := t.db.View(func(txn *badger.Txn) error {
+		for _, prefix := range prefixes {
+			it := txn.NewIterator(iopts)
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				key := string(it.Item().Key())
+				current[key] = true
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				fn([]byte(key))
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for key := range seen {
+		if !current[key] {
+			delete(seen, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"math"
+)
+
+// TermCodec encodes the Go values passed to Put/Get/PutQuad/GetQuad (and
+// the terms a caller like rdfio builds) into bytes for storage, and
+// decodes them back. A Triplestore's codec is fixed at New time via
+// WithCodec.
+type TermCodec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(b []byte) (interface{}, error)
+}
+
+// termKind tags which shape an encoded term holds, so Decode doesn't have
+// to guess from the bytes alone.
+type termKind byte
+
+const (
+	kindString termKind = iota
+	kindInt64
+	kindFloat64
+	kindBool
+	kindBytes
+	kindTerm  // an RLPTerm, field-wise encoded as an RLP list
+	kindOther // anything else: gob-encoded
+)
+
+func kindOf(v interface{}) termKind {
+	switch v.(type) {
+	case string:
+		return kindString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return kindInt64
+	case float32, float64:
+		return kindFloat64
+	case bool:
+		return kindBool
+	case []byte:
+		return kindBytes
+	default:
+		return kindOther
+	}
+}
+
+func asInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	default:
+		return 0
+	}
+}
+
+func asFloat64(v interface{}) float64 {
+	switch f := v.(type) {
+	case float32:
+		return float64(f)
+	case float64:
+		return f
+	default:
+		return 0
+	}
+}
+
+// gobCodec is the historical default: terms are gob-encoded, wrapped in
+// an interface{} so arbitrary Go values round-trip.
+type gobCodec struct{}
+
+// GobCodec returns the default TermCodec, used when New is given no
+// WithCodec option.
+func GobCodec() TermCodec { return gobCodec{} }
+
+func (gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(b []byte) (interface{}, error) {
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func init() {
+	// Concrete types stored in a Triplestore term's interface{} must be
+	// registered for gob to decode them back into an interface{} value.
+	gob.Register("")
+	gob.Register(int64(0))
+	gob.Register(float64(0))
+	gob.Register(false)
+	gob.Register([]byte{})
+}
+
+// rlpCodec encodes terms using RLP-style length-prefixed, self-delimiting
+// byte strings - the same framing ethereum-style tooling expects - with a
+// one-byte kind tag ahead of the RLP payload so Decode knows how to
+// reinterpret it. Numeric terms are kept in canonical big-endian form.
+type rlpCodec struct{}
+
+// RLPCodec returns a TermCodec using RLP-style length-prefixed,
+// self-delimiting encoding, useful for interop with ethereum-style
+// tooling.
+func RLPCodec() TermCodec { return rlpCodec{} }
+
+// RLPTerm is implemented by a term type that wants a real field-wise RLP
+// encoding under RLPCodec, rather than falling back to a gob-encoded
+// blob wrapped in an RLP byte string. rdfio.Term implements it.
+type RLPTerm interface {
+	// RLPFields returns the term's fields in a fixed order, each as a
+	// plain byte string, for rlpCodec to wrap as an RLP list.
+	RLPFields() [][]byte
+}
+
+// rlpTermDecoder, once set by RegisterRLPTermDecoder, turns the field
+// list an RLPTerm.RLPFields produced back into the original concrete
+// term type. database can't import the package that type lives in
+// (rdfio already imports database), so that package registers its own
+// decoder here instead.
+var rlpTermDecoder func(fields [][]byte) (interface{}, error)
+
+// RegisterRLPTermDecoder sets the decoder rlpCodec uses to reconstruct
+// an RLPTerm from its encoded fields. Call it from the package that
+// defines the RLPTerm implementation, e.g. rdfio's init.
+func RegisterRLPTermDecoder(decode func(fields [][]byte) (interface{}, error)) {
+	rlpTermDecoder = decode
+}
+
+func (rlpCodec) Encode(v interface{}) ([]byte, error) {
+	if rt, ok := v.(RLPTerm); ok {
+		return append([]byte{byte(kindTerm)}, rlpEncodeList(rt.RLPFields())...), nil
+	}
+
+	kind := kindOf(v)
+
+	var payload []byte
+	switch kind {
+	case kindString:
+		payload = []byte(v.(string))
+	case kindInt64:
+		payload = minimalBigEndian(uint64(asInt64(v)))
+	case kindFloat64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(asFloat64(v)))
+		payload = b
+	case kindBool:
+		if v.(bool) {
+			payload = []byte{1}
+		} else {
+			payload = []byte{0}
+		}
+	case kindBytes:
+		payload = v.([]byte)
+	default:
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+			return nil, err
+		}
+		payload = buf.Bytes()
+	}
+
+	return append([]byte{byte(kind)}, rlpEncodeBytes(payload)...), nil
+}
+
+func (rlpCodec) Decode(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("rlp: empty term")
+	}
+	kind := termKind(b[0])
+
+	if kind == kindTerm {
+		fields, err := rlpDecodeList(b[1:])
+		if err != nil {
+			return nil, err
+		}
+		if rlpTermDecoder == nil {
+			return nil, fmt.Errorf("rlp: no RLPTerm decoder registered")
+		}
+		return rlpTermDecoder(fields)
+	}
+
+	payload, _, err := rlpDecodeBytes(b[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case kindString:
+		return string(payload), nil
+	case kindInt64:
+		return int64(bigEndianToUint64(payload)), nil
+	case kindFloat64:
+		if len(payload) != 8 {
+			return nil, fmt.Errorf("rlp: malformed float64 term")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), nil
+	case kindBool:
+		return len(payload) > 0 && payload[0] != 0, nil
+	case kindBytes:
+		return payload, nil
+	default:
+		var v interface{}
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// rlpEncodeBytes encodes payload as a canonical RLP byte string: a single
+// byte under 0x80 encodes itself, a string under 56 bytes is prefixed
+// with 0x80+length, and a longer string is prefixed with 0xb7 plus the
+// byte count of its big-endian length, followed by that length.
+func rlpEncodeBytes(payload []byte) []byte {
+	if len(payload) == 1 && payload[0] < 0x80 {
+		return payload
+	}
+	if len(payload) < 56 {
+		return append([]byte{0x80 + byte(len(payload))}, payload...)
+	}
+	lenBytes := minimalBigEndian(uint64(len(payload)))
+	out := append([]byte{0xb7 + byte(len(lenBytes))}, lenBytes...)
+	return append(out, payload...)
+}
+
+// rlpDecodeBytes is the inverse of rlpEncodeBytes. It returns the decoded
+// payload and how many bytes of b it consumed.
+func rlpDecodeBytes(b []byte) ([]byte, int, error) {
+	if len(b) == 0 {
+		return nil, 0, fmt.Errorf("rlp: truncated term")
+	}
+	switch {
+	case b[0] < 0x80:
+		return b[0:1], 1, nil
+	case b[0] < 0xb8:
+		n := int(b[0] - 0x80)
+		if len(b) < 1+n {
+			return nil, 0, fmt.Errorf("rlp: truncated byte string")
+		}
+		return b[1 : 1+n], 1 + n, nil
+	default:
+		lenOfLen := int(b[0] - 0xb7)
+		if len(b) < 1+lenOfLen {
+			return nil, 0, fmt.Errorf("rlp: truncated length header")
+		}
+		n := int(bigEndianToUint64(b[1 : 1+lenOfLen]))
+		if len(b) < 1+lenOfLen+n {
+			return nil, 0, fmt.Errorf("rlp: truncated byte string")
+		}
+		start := 1 + lenOfLen
+		return b[start : start+n], start + n, nil
+	}
+}
+
+// rlpEncodeList encodes items as a canonical RLP list: each item is
+// framed as its own RLP byte string (rlpEncodeBytes) and concatenated,
+// then the concatenation is given a list length header using the same
+// short/long scheme rlpEncodeBytes uses, just starting at 0xc0 instead
+// of 0x80.
+func rlpEncodeList(items [][]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, rlpEncodeBytes(item)...)
+	}
+	if len(payload) < 56 {
+		return append([]byte{0xc0 + byte(len(payload))}, payload...)
+	}
+	lenBytes := minimalBigEndian(uint64(len(payload)))
+	out := append([]byte{0xf7 + byte(len(lenBytes))}, lenBytes...)
+	return append(out, payload...)
+}
+
+// rlpDecodeList is the inverse of rlpEncodeList.
+func rlpDecodeList(b []byte) ([][]byte, error) {
+	if len(b) == 0 {
+		return nil, fmt.Errorf("rlp: truncated list")
+	}
+
+	var payload []byte
+	switch {
+	case b[0] < 0xc0:
+		return nil, fmt.Errorf("rlp: expected list, got byte string")
+	case b[0] < 0xf8:
+		n := int(b[0] - 0xc0)
+		if len(b) < 1+n {
+			return nil, fmt.Errorf("rlp: truncated list")
+		}
+		payload = b[1 : 1+n]
+	default:
+		lenOfLen := int(b[0] - 0xf7)
+		if len(b) < 1+lenOfLen {
+			return nil, fmt.Errorf("rlp: truncated list length header")
+		}
+		n := int(bigEndianToUint64(b[1 : 1+lenOfLen]))
+		start := 1 + lenOfLen
+		if len(b) < start+n {
+			return nil, fmt.Errorf("rlp: truncated list")
+		}
+		payload = b[start : start+n]
+	}
+
+	var items [][]byte
+	for len(payload) > 0 {
+		item, n, err := rlpDecodeBytes(payload)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		payload = payload[n:]
+	}
+	return items, nil
+}
+
+// minimalBigEndian returns n's big-endian representation with leading
+// zero bytes stripped, as RLP requires for canonical encoding. Zero is
+// returned as a single 0x00 byte, not as an empty slice.
+func minimalBigEndian(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	i := 0
+	for i < len(b)-1 && b[i] == 0 {
+		i++
+	}
+	return b[i:]
+}
+
+func bigEndianToUint64(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
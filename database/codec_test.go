@@ -0,0 +1,40 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import "testing"
+
+type testRLPTerm struct {
+	kind  byte
+	value string
+}
+
+func (t testRLPTerm) RLPFields() [][]byte {
+	return [][]byte{{t.kind}, []byte(t.value)}
+}
+
+func TestRLPCodecTermRoundTrip(t *testing.T) {
+	prev := rlpTermDecoder
+	defer func() { rlpTermDecoder = prev }()
+
+	RegisterRLPTermDecoder(func(fields [][]byte) (interface{}, error) {
+		return testRLPTerm{kind: fields[0][0], value: string(fields[1])}, nil
+	})
+
+	codec := RLPCodec()
+	want := testRLPTerm{kind: 2, value: "hello"}
+
+	b, err := codec.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := codec.Decode(b)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got != interface{}(want) {
+		t.Fatalf("round-trip = %#v, want %#v", got, want)
+	}
+}
@@ -15,6 +15,8 @@ import (
 type Triplestore struct {
 	db    *badger.DB
 	iopts badger.IteratorOptions
+	codec TermCodec
+	dir   string
 }
 
 const hashKeySpace = uint64(9223372036854775807)
@@ -22,14 +24,18 @@ const hashKeySpace = uint64(9223372036854775807)
 type FieldPrefix []byte
 
 var (
-	dbEmpty   FieldPrefix = []byte{}
-	dbKey     FieldPrefix = []byte{0}
-	dbSPO     FieldPrefix = []byte{1}
-	dbPOS     FieldPrefix = []byte{2}
-	dbSOP     FieldPrefix = []byte{3}
-	dbValue   FieldPrefix = []byte{4}
-	dbHash    FieldPrefix = []byte{5}
-	dbHashKey FieldPrefix = []byte{6}
+	dbEmpty    FieldPrefix = []byte{}
+	dbKey      FieldPrefix = []byte{0}
+	dbSPO      FieldPrefix = []byte{1}
+	dbPOS      FieldPrefix = []byte{2}
+	dbSOP      FieldPrefix = []byte{3}
+	dbValue    FieldPrefix = []byte{4}
+	dbHash     FieldPrefix = []byte{5}
+	dbHashKey  FieldPrefix = []byte{6}
+	dbRefCount FieldPrefix = []byte{7}
+	dbGSPO     FieldPrefix = []byte{8}
+	dbGPOS     FieldPrefix = []byte{9}
+	dbGSOP     FieldPrefix = []byte{10}
 )
 
 type Field int
@@ -40,26 +46,62 @@ var (
 	Object    Field = 2
 )
 
-func New() *Triplestore {
-	opts := badger.DefaultOptions
-	opts.Dir = "data"
-	opts.ValueDir = "data"
-	db, err := badger.Open(opts)
+// Option configures a Triplestore constructed by New.
+type Option func(*Triplestore)
+
+// WithCodec selects the TermCodec used to encode and decode terms.
+// The default, used when WithCodec is not given, is GobCodec().
+func WithCodec(c TermCodec) Option {
+	return func(t *Triplestore) {
+		t.codec = c
+	}
+}
+
+// WithDir selects the directory Badger stores its data in. The default,
+// used when WithDir is not given, is "data".
+func WithDir(dir string) Option {
+	return func(t *Triplestore) {
+		t.dir = dir
+	}
+}
+
+func New(opts ...Option) *Triplestore {
+	t := &Triplestore{dir: "data", codec: GobCodec()}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	bopts := badger.DefaultOptions
+	bopts.Dir = t.dir
+	bopts.ValueDir = t.dir
+	db, err := badger.Open(bopts)
 	if err != nil {
 		log.Fatal(err)
 	}
-	var iopts = badger.DefaultIteratorOptions
-	//iopts.PrefetchValues = false
-	return &Triplestore{
-		db:    db,
-		iopts: iopts,
-	}
+	t.db = db
+	t.iopts = badger.DefaultIteratorOptions
+	return t
 }
 
 func (t *Triplestore) Close() {
 	t.db.Close()
 }
 
+// marshal encodes v using the store's configured TermCodec.
+func (t *Triplestore) marshal(v interface{}) ([]byte, error) {
+	return t.codec.Encode(v)
+}
+
+// unmarshal decodes b using the store's configured TermCodec, panicking
+// on failure to match Materialize's existing panic-on-corruption style.
+func (t *Triplestore) unmarshal(b []byte) interface{} {
+	v, err := t.codec.Decode(b)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
 func (t *Triplestore) Get(subject interface{}, predicate interface{}, object interface{}) ([][]byte, error) {
 	txn := t.db.NewTransaction(false)
 	defer txn.Discard()
@@ -133,10 +175,19 @@ func (t *Triplestore) toKeys(txn *badger.Txn, input interface{}, create bool) ([
 	case nil:
 		res = [][]byte{nil}
 	default:
-		s, err := marshal(input)
+		s, err := t.marshal(input)
 		if err != nil {
 			return nil, err
 		}
+		if !create {
+			// A term that isn't interned (e.g. garbage-collected by
+			// Delete) matches nothing, rather than being an error.
+			si, err := t.getIdIfExists(txn, s)
+			if err != nil || si == nil {
+				return [][]uint8{}, err
+			}
+			return [][]uint8{si}, nil
+		}
 		si, err := t.getId(txn, s, create)
 		if err != nil {
 			return nil, err
@@ -151,15 +202,27 @@ func (t *Triplestore) Put(subject interface{}, predicate interface{}, object int
 	txn := t.db.NewTransaction(true)
 	defer txn.Discard()
 
-	s, err := marshal(subject)
+	if err := t.putTxn(txn, subject, predicate, object); err != nil {
+		return err
+	}
+
+	var err error
+	txn.Commit(func(e error) {
+		err = e
+	})
+	return err
+}
+
+func (t *Triplestore) putTxn(txn *badger.Txn, subject interface{}, predicate interface{}, object interface{}) error {
+	s, err := t.marshal(subject)
 	if err != nil {
 		return err
 	}
-	p, err := marshal(predicate)
+	p, err := t.marshal(predicate)
 	if err != nil {
 		return err
 	}
-	o, err := marshal(object)
+	o, err := t.marshal(object)
 	if err != nil {
 		return err
 	}
@@ -177,17 +240,226 @@ func (t *Triplestore) Put(subject interface{}, predicate interface{}, object int
 		return err
 	}
 
-	err = t.put(txn, si, pi, oi)
+	return t.put(txn, si, pi, oi)
+}
+
+// PutAll bulk-inserts triples, committing a read-write transaction every
+// batchSize triples instead of once per triple. Badger's WriteBatch can't
+// be used here: interning a term requires reading its existing ID before
+// deciding whether to create one, and WriteBatch offers no reads. Grouping
+// several triples per transaction commit still amortizes most of the
+// per-triple overhead for bulk loaders such as rdfio.
+func (t *Triplestore) PutAll(triples [][3]interface{}, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var n int64
+	for len(triples) > 0 {
+		end := batchSize
+		if end > len(triples) {
+			end = len(triples)
+		}
+		chunk := triples[:end]
+		triples = triples[end:]
+
+		txn := t.db.NewTransaction(true)
+		for _, tr := range chunk {
+			if err := t.putTxn(txn, tr[0], tr[1], tr[2]); err != nil {
+				txn.Discard()
+				return n, err
+			}
+		}
+		var err error
+		txn.Commit(func(e error) {
+			err = e
+		})
+		if err != nil {
+			return n, err
+		}
+		n += int64(len(chunk))
+	}
+	return n, nil
+}
+func (t *Triplestore) put(txn *badger.Txn, si []byte, pi []byte, oi []byte) error {
+	exists, err := t.keyExists(txn, zcopy(dbSPO, si, pi, oi))
+	if err != nil {
+		return err
+	}
+
+	combinations := [][][]byte{
+		{dbSPO, si, pi, oi},
+		{dbSOP, si, oi, pi},
+		{dbPOS, pi, oi, si},
+	}
+	for _, i := range combinations {
+		err := txn.Set(zcopy(i...), []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	if exists {
+		return nil
+	}
+	for _, id := range [][]byte{si, pi, oi} {
+		if err := t.incRef(txn, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyExists reports whether key is already present, without fetching its
+// value.
+func (t *Triplestore) keyExists(txn *badger.Txn, key []byte) (bool, error) {
+	_, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes the triple (subject, predicate, object) from all three
+// index prefixes within a single read-write transaction. Terms that are
+// no longer referenced by any remaining triple are garbage-collected.
+// Deleting a triple that does not exist is a no-op.
+func (t *Triplestore) Delete(subject interface{}, predicate interface{}, object interface{}) error {
+	txn := t.db.NewTransaction(true)
+	defer txn.Discard()
+
+	si, pi, oi, err := t.resolveIds(txn, subject, predicate, object)
 	if err != nil {
 		return err
 	}
+	if si == nil || pi == nil || oi == nil {
+		return nil
+	}
+
+	if err := t.delete(txn, si, pi, oi); err != nil {
+		return err
+	}
 
 	txn.Commit(func(e error) {
 		err = e
 	})
 	return err
 }
-func (t *Triplestore) put(txn *badger.Txn, si []byte, pi []byte, oi []byte) error {
+
+// Update atomically replaces (oldS, oldP, oldO) with (newS, newP, newO),
+// as a delete of the old triple followed by a put of the new one in a
+// single transaction. If the old triple does not exist, only the new
+// triple is inserted.
+func (t *Triplestore) Update(oldS interface{}, oldP interface{}, oldO interface{}, newS interface{}, newP interface{}, newO interface{}) error {
+	txn := t.db.NewTransaction(true)
+	defer txn.Discard()
+
+	si, pi, oi, err := t.resolveIds(txn, oldS, oldP, oldO)
+	if err != nil {
+		return err
+	}
+	if si != nil && pi != nil && oi != nil {
+		if err := t.delete(txn, si, pi, oi); err != nil {
+			return err
+		}
+	}
+
+	ns, err := t.marshal(newS)
+	if err != nil {
+		return err
+	}
+	np, err := t.marshal(newP)
+	if err != nil {
+		return err
+	}
+	no, err := t.marshal(newO)
+	if err != nil {
+		return err
+	}
+
+	nsi, err := t.getId(txn, ns, true)
+	if err != nil {
+		return err
+	}
+	npi, err := t.getId(txn, np, true)
+	if err != nil {
+		return err
+	}
+	noi, err := t.getId(txn, no, true)
+	if err != nil {
+		return err
+	}
+
+	if err := t.put(txn, nsi, npi, noi); err != nil {
+		return err
+	}
+
+	txn.Commit(func(e error) {
+		err = e
+	})
+	return err
+}
+
+// resolveIds looks up the interned IDs for subject, predicate and object
+// without creating them. Any term not already present in the store
+// yields a nil ID.
+func (t *Triplestore) resolveIds(txn *badger.Txn, subject interface{}, predicate interface{}, object interface{}) ([]byte, []byte, []byte, error) {
+	s, err := t.marshal(subject)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p, err := t.marshal(predicate)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	o, err := t.marshal(object)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	si, err := t.getIdIfExists(txn, s)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pi, err := t.getIdIfExists(txn, p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	oi, err := t.getIdIfExists(txn, o)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return si, pi, oi, nil
+}
+
+// getIdIfExists looks up the interned ID for value without creating it,
+// returning a nil ID (and nil error) when the value is not yet interned.
+func (t *Triplestore) getIdIfExists(txn *badger.Txn, value []byte) ([]byte, error) {
+	id, err := t.getId(txn, value, false)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	return id, err
+}
+
+// delete removes the (si, pi, oi) triple's three index rows and drops a
+// reference from each of its terms. si, pi and oi are each individually
+// known to be interned (see resolveIds), but that doesn't mean this
+// specific combination was ever Put - e.g. si and oi might only coexist
+// in some other triple's indexes. So delete confirms the SPO row for this
+// exact combination exists before touching anything; otherwise terms
+// still referenced by other live triples could be decremented to zero
+// and garbage-collected out from under them.
+func (t *Triplestore) delete(txn *badger.Txn, si []byte, pi []byte, oi []byte) error {
+	exists, err := t.keyExists(txn, zcopy(dbSPO, si, pi, oi))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
 
 	combinations := [][][]byte{
 		{dbSPO, si, pi, oi},
@@ -195,14 +467,98 @@ func (t *Triplestore) put(txn *badger.Txn, si []byte, pi []byte, oi []byte) erro
 		{dbPOS, pi, oi, si},
 	}
 	for _, i := range combinations {
-		err := txn.Set(zcopy(i...), []byte{})
+		err := txn.Delete(zcopy(i...))
 		if err != nil {
 			return err
 		}
 	}
+	for _, id := range [][]byte{si, pi, oi} {
+		if err := t.decRef(txn, id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// incRef bumps the reference count for the interned term id, creating
+// the counter if this is its first reference.
+func (t *Triplestore) incRef(txn *badger.Txn, id []byte) error {
+	count, err := t.refCount(txn, id)
+	if err != nil {
+		return err
+	}
+	return t.setRefCount(txn, id, count+1)
+}
+
+// decRef drops the reference count for the interned term id. Once the
+// count reaches zero the underlying dbKey/dbValue (or dbHash/dbHashKey)
+// rows are garbage-collected so the ID is not leaked forever.
+func (t *Triplestore) decRef(txn *badger.Txn, id []byte) error {
+	count, err := t.refCount(txn, id)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return t.gcTerm(txn, id)
+	}
+	return t.setRefCount(txn, id, count-1)
+}
+
+func (t *Triplestore) refCount(txn *badger.Txn, id []byte) (uint64, error) {
+	item, err := txn.Get(zcopy(dbRefCount, id))
+	if err == badger.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var count uint64
+	err = item.Value(func(val []byte) {
+		count = binary.LittleEndian.Uint64(val)
+	})
+	return count, err
+}
+
+func (t *Triplestore) setRefCount(txn *badger.Txn, id []byte, count uint64) error {
+	val := make([]byte, 8)
+	binary.LittleEndian.PutUint64(val, count)
+	return txn.Set(zcopy(dbRefCount, id), val)
+}
+
+// gcTerm removes the interned term's forward and reverse lookup rows,
+// plus its now-zero reference counter.
+func (t *Triplestore) gcTerm(txn *badger.Txn, id []byte) error {
+	item, err := txn.Get(id)
+	if err != nil {
+		return err
+	}
+	var stored []byte
+	err = item.Value(func(val []byte) {
+		stored = append([]byte{}, val...)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Delete(id); err != nil {
+		return err
+	}
+
+	switch id[0] {
+	case dbHashKey[0]:
+		hash := sha256.Sum256(stored)
+		if err := txn.Delete(append(dbHash, hash[:]...)); err != nil {
+			return err
+		}
+	default:
+		if err := txn.Delete(stored); err != nil {
+			return err
+		}
+	}
+
+	return txn.Delete(zcopy(dbRefCount, id))
+}
+
 func zcopy(slices ...[]byte) []byte {
 	var totalLen int
 	for _, s := range slices {
@@ -268,7 +624,10 @@ func store(txn *badger.Txn, key uint64, value []byte) ([]byte, error) {
 
 	// If the value length > 40, generate a sha256 for the lookup
 	if len(value) > 40 {
-		binary.LittleEndian.PutUint64(k[1:9], key+hashKeySpace)
+		// BigEndian so that Seek over dbHashKey rows yields IDs in
+		// insertion order, rather than the effectively random order
+		// LittleEndian byte-sorting gave.
+		binary.BigEndian.PutUint64(k[1:9], key+hashKeySpace)
 		k[0] = dbHashKey[0]
 		hash := sha256.Sum256(value)
 		raw = append(dbHash, hash[:]...)
@@ -281,7 +640,10 @@ func store(txn *badger.Txn, key uint64, value []byte) ([]byte, error) {
 			return nil, err
 		}
 	} else {
-		binary.LittleEndian.PutUint64(k[1:9], key)
+		// BigEndian so that Seek over dbKey rows yields IDs in insertion
+		// order, rather than the effectively random order LittleEndian
+		// byte-sorting gave.
+		binary.BigEndian.PutUint64(k[1:9], key)
 		k[0] = dbKey[0]
 		err := txn.Set(k, value)
 		if err != nil {
@@ -307,11 +669,11 @@ func (t *Triplestore) Materialize(keys [][]byte) []interface{} {
 				if val[0] == dbHash[0] {
 					value2, _ := txn.Get(val)
 					value2.Value(func(val2 []byte) {
-						rval := unmarshal(val[1:])
+						rval := t.unmarshal(val[1:])
 						res[j] = rval
 					})
 				} else {
-					rval := unmarshal(val[1:])
+					rval := t.unmarshal(val[1:])
 					res[j] = rval
 				}
 			})
@@ -322,4 +684,396 @@ func (t *Triplestore) Materialize(keys [][]byte) []interface{} {
 		panic(err)
 	}
 	return res
+}
+
+// Each streams every triple in the default graph to fn in SPO order,
+// materializing one triple at a time rather than loading the whole graph
+// into memory. Iteration stops early if fn returns false.
+func (t *Triplestore) Each(fn func(subject interface{}, predicate interface{}, object interface{}) bool) error {
+	return t.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(t.iopts)
+		defer it.Close()
+		for it.Seek(dbSPO); it.ValidForPrefix(dbSPO); it.Next() {
+			rest := []byte(it.Item().Key())[len(dbSPO):]
+			if len(rest) != 27 {
+				continue
+			}
+			vals := t.Materialize([][]byte{rest[0:9], rest[9:18], rest[18:27]})
+			if !fn(vals[0], vals[1], vals[2]) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// EachQuad streams every quad to fn, the default graph first (with a nil
+// graph term) followed by every named graph. Iteration stops early if fn
+// returns false.
+func (t *Triplestore) EachQuad(fn func(subject interface{}, predicate interface{}, object interface{}, graph interface{}) bool) error {
+	more := true
+	err := t.Each(func(s, p, o interface{}) bool {
+		more = fn(s, p, o, nil)
+		return more
+	})
+	if err != nil || !more {
+		return err
+	}
+
+	return t.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(t.iopts)
+		defer it.Close()
+		for it.Seek(dbGSPO); it.ValidForPrefix(dbGSPO); it.Next() {
+			rest := []byte(it.Item().Key())[len(dbGSPO):]
+			if len(rest) != 36 {
+				continue
+			}
+			vals := t.Materialize([][]byte{rest[9:18], rest[18:27], rest[27:36], rest[0:9]})
+			if !fn(vals[0], vals[1], vals[2], vals[3]) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// PutQuad stores (subject, predicate, object, graph) as a quad. A nil
+// graph stores the triple in the default graph, i.e. the plain SPO/POS/SOP
+// indexes used by Put.
+func (t *Triplestore) PutQuad(subject interface{}, predicate interface{}, object interface{}, graph interface{}) error {
+	if graph == nil {
+		return t.Put(subject, predicate, object)
+	}
+
+	txn := t.db.NewTransaction(true)
+	defer txn.Discard()
+
+	si, pi, oi, gi, err := t.internQuad(txn, subject, predicate, object, graph)
+	if err != nil {
+		return err
+	}
+
+	if err := t.putQuad(txn, si, pi, oi, gi); err != nil {
+		return err
+	}
+
+	txn.Commit(func(e error) {
+		err = e
+	})
+	return err
+}
+
+// PutQuadAll bulk-inserts quads, committing a transaction every batchSize
+// quads for the same reasons PutAll batches triples. A nil graph in any
+// entry inserts that quad into the default graph.
+func (t *Triplestore) PutQuadAll(quads [][4]interface{}, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	var n int64
+	for len(quads) > 0 {
+		end := batchSize
+		if end > len(quads) {
+			end = len(quads)
+		}
+		chunk := quads[:end]
+		quads = quads[end:]
+
+		txn := t.db.NewTransaction(true)
+		for _, q := range chunk {
+			if q[3] == nil {
+				if err := t.putTxn(txn, q[0], q[1], q[2]); err != nil {
+					txn.Discard()
+					return n, err
+				}
+				continue
+			}
+			si, pi, oi, gi, err := t.internQuad(txn, q[0], q[1], q[2], q[3])
+			if err != nil {
+				txn.Discard()
+				return n, err
+			}
+			if err := t.putQuad(txn, si, pi, oi, gi); err != nil {
+				txn.Discard()
+				return n, err
+			}
+		}
+		var err error
+		txn.Commit(func(e error) {
+			err = e
+		})
+		if err != nil {
+			return n, err
+		}
+		n += int64(len(chunk))
+	}
+	return n, nil
+}
+
+func (t *Triplestore) internQuad(txn *badger.Txn, subject interface{}, predicate interface{}, object interface{}, graph interface{}) (si []byte, pi []byte, oi []byte, gi []byte, err error) {
+	s, err := t.marshal(subject)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	p, err := t.marshal(predicate)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	o, err := t.marshal(object)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	g, err := t.marshal(graph)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	si, err = t.getId(txn, s, true)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	pi, err = t.getId(txn, p, true)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	oi, err = t.getId(txn, o, true)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	gi, err = t.getId(txn, g, true)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return si, pi, oi, gi, nil
+}
+
+func (t *Triplestore) putQuad(txn *badger.Txn, si []byte, pi []byte, oi []byte, gi []byte) error {
+	exists, err := t.keyExists(txn, zcopy(dbGSPO, gi, si, pi, oi))
+	if err != nil {
+		return err
+	}
+
+	combinations := [][][]byte{
+		{dbGSPO, gi, si, pi, oi},
+		{dbGPOS, gi, pi, oi, si},
+		{dbGSOP, gi, si, oi, pi},
+	}
+	for _, i := range combinations {
+		if err := txn.Set(zcopy(i...), []byte{}); err != nil {
+			return err
+		}
+	}
+	if exists {
+		return nil
+	}
+	for _, id := range [][]byte{si, pi, oi, gi} {
+		if err := t.incRef(txn, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuad returns the matching quads as parallel slices of term IDs and
+// graph IDs. Like Get, it returns one term ID per match rather than the
+// whole triple key: the last component of the index key scanned, which
+// for a named-graph match found with a nil graph is always the object's
+// ID. A nil graph means "any graph" and searches both the default graph
+// and every named graph; the graph ID for a default-graph match is nil.
+func (t *Triplestore) GetQuad(subject interface{}, predicate interface{}, object interface{}, graph interface{}) ([][]byte, [][]byte, error) {
+	txn := t.db.NewTransaction(false)
+	defer txn.Discard()
+
+	sarr, err := t.toKeys(txn, subject, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	parr, err := t.toKeys(txn, predicate, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	oarr, err := t.toKeys(txn, object, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if graph == nil {
+		triples, err := t.Get(subject, predicate, object)
+		if err != nil {
+			return nil, nil, err
+		}
+		graphs := make([][]byte, len(triples))
+
+		anyTriples := make(chan []byte)
+		anyGraphs := make(chan []byte)
+		go t.getAnyGraph(txn, sarr, parr, oarr, anyTriples, anyGraphs)
+		for tr := range anyTriples {
+			triples = append(triples, tr)
+			graphs = append(graphs, <-anyGraphs)
+		}
+		return triples, graphs, nil
+	}
+
+	g, err := t.marshal(graph)
+	if err != nil {
+		return nil, nil, err
+	}
+	gi, err := t.getIdIfExists(txn, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gi == nil {
+		return nil, nil, nil
+	}
+
+	triples := make([][]byte, 0)
+	quads := make(chan []byte)
+	go t.getQuad(txn, gi, sarr, parr, oarr, quads)
+	for tr := range quads {
+		triples = append(triples, tr[len(tr)-9:])
+	}
+	graphs := make([][]byte, len(triples))
+	for i := range graphs {
+		graphs[i] = gi
+	}
+	return triples, graphs, nil
+}
+
+func (t *Triplestore) getQuad(txn *badger.Txn, gi []byte, sarr [][]byte, parr [][]byte, oarr [][]byte, triples chan []byte) {
+	for _, si := range sarr {
+		for _, pi := range parr {
+			for _, oi := range oarr {
+				var prefix []byte
+				switch {
+				case si != nil && pi != nil && oi != nil:
+					prefix = zcopy(dbGSPO, gi, si, pi, oi)
+				case si != nil && pi != nil:
+					prefix = zcopy(dbGSPO, gi, si, pi)
+				case pi != nil && oi != nil:
+					prefix = zcopy(dbGPOS, gi, pi, oi)
+				case si != nil && oi != nil:
+					prefix = zcopy(dbGSOP, gi, si, oi)
+				default:
+					prefix = zcopy(dbGSPO, gi)
+				}
+				it := txn.NewIterator(t.iopts)
+				for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+					triples <- []byte(it.Item().Key())
+				}
+				it.Close()
+			}
+		}
+	}
+	close(triples)
+}
+
+// getAnyGraph scans every named graph (graph unbound) for matches,
+// filtering client-side since the graph ID is the leading component of
+// the GSPO index and so cannot be skipped when unbound.
+func (t *Triplestore) getAnyGraph(txn *badger.Txn, sarr [][]byte, parr [][]byte, oarr [][]byte, triples chan []byte, graphs chan []byte) {
+	it := txn.NewIterator(t.iopts)
+	defer it.Close()
+
+	for it.Seek(dbGSPO); it.ValidForPrefix(dbGSPO); it.Next() {
+		key := []byte(it.Item().Key())
+		rest := key[len(dbGSPO):]
+		if len(rest) != 4*9 {
+			continue
+		}
+		gi := rest[0:9]
+		si := rest[9:18]
+		pi := rest[18:27]
+		oi := rest[27:36]
+
+		if !matchesAny(sarr, si) || !matchesAny(parr, pi) || !matchesAny(oarr, oi) {
+			continue
+		}
+		triples <- oi
+		graphs <- gi
+	}
+	close(triples)
+	close(graphs)
+}
+
+func matchesAny(bound [][]byte, id []byte) bool {
+	for _, b := range bound {
+		if b == nil || bytesEqual(b, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func bytesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DeleteQuad removes (subject, predicate, object, graph) from the quad
+// indexes. A nil graph deletes from the default graph, i.e. behaves like
+// Delete.
+func (t *Triplestore) DeleteQuad(subject interface{}, predicate interface{}, object interface{}, graph interface{}) error {
+	if graph == nil {
+		return t.Delete(subject, predicate, object)
+	}
+
+	txn := t.db.NewTransaction(true)
+	defer txn.Discard()
+
+	g, err := t.marshal(graph)
+	if err != nil {
+		return err
+	}
+	gi, err := t.getIdIfExists(txn, g)
+	if err != nil {
+		return err
+	}
+	if gi == nil {
+		return nil
+	}
+
+	si, pi, oi, err := t.resolveIds(txn, subject, predicate, object)
+	if err != nil {
+		return err
+	}
+	if si == nil || pi == nil || oi == nil {
+		return nil
+	}
+
+	exists, err := t.keyExists(txn, zcopy(dbGSPO, gi, si, pi, oi))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+
+	combinations := [][][]byte{
+		{dbGSPO, gi, si, pi, oi},
+		{dbGPOS, gi, pi, oi, si},
+		{dbGSOP, gi, si, oi, pi},
+	}
+	for _, i := range combinations {
+		if err := txn.Delete(zcopy(i...)); err != nil {
+			return err
+		}
+	}
+	for _, id := range [][]byte{si, pi, oi, gi} {
+		if err := t.decRef(txn, id); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit(func(e error) {
+		err = e
+	})
+	return err
 }
\ No newline at end of file
@@ -0,0 +1,142 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import "testing"
+
+func newTestStore(t *testing.T) *Triplestore {
+	t.Helper()
+	ts := New(WithDir(t.TempDir()))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestPutGetDelete(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Get returned %d triples, want 1", len(got))
+	}
+
+	if err := ts.Delete("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	got, err = ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Get after Delete returned %d triples, want 0", len(got))
+	}
+}
+
+// TestDeleteDoesNotGCSharedTerms checks that deleting a triple whose terms
+// are each individually interned by other, unrelated triples does not
+// garbage-collect those terms out from under the triples that still
+// reference them.
+func TestDeleteDoesNotGCSharedTerms(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Bob", "worksAt", "Corp"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// (Alice, worksAt, Corp) was never Put: every term exists, but not
+	// this combination. Deleting it must be a no-op.
+	if err := ts.Delete("Alice", "worksAt", "Corp"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	got, err := ts.Get("Bob", "worksAt", "Corp")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("(Bob, worksAt, Corp) was garbage-collected: Get returned %d triples, want 1", len(got))
+	}
+
+	got, err = ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("(Alice, knows, Bob) was garbage-collected: Get returned %d triples, want 1", len(got))
+	}
+}
+
+// TestPutTwiceThenDeleteOnceRemovesTriple checks that repeat Puts of the
+// same triple don't inflate its terms' reference counts beyond what a
+// single Delete can unwind.
+func TestPutTwiceThenDeleteOnceRemovesTriple(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put (repeat): %v", err)
+	}
+
+	if err := ts.Delete("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	got, err := ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Get after Delete returned %d triples, want 0", len(got))
+	}
+
+	// Re-putting the triple after its terms were garbage-collected must
+	// intern them afresh and store the triple again.
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put after Delete: %v", err)
+	}
+	got, err = ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Get after re-Put returned %d triples, want 1", len(got))
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.Update("Alice", "knows", "Bob", "Alice", "knows", "Carol"); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, err := ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("old triple still present after Update: got %d, want 0", len(got))
+	}
+	got, err = ts.Get("Alice", "knows", "Carol")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("new triple missing after Update: got %d, want 1", len(got))
+	}
+}
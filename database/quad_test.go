@@ -0,0 +1,173 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import "testing"
+
+func TestPutGetDeleteQuad(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.PutQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("PutQuad: %v", err)
+	}
+
+	triples, graphs, err := ts.GetQuad("Alice", "knows", "Bob", "Graph1")
+	if err != nil {
+		t.Fatalf("GetQuad: %v", err)
+	}
+	if len(triples) != 1 || len(graphs) != 1 {
+		t.Fatalf("GetQuad returned %d triples / %d graphs, want 1/1", len(triples), len(graphs))
+	}
+
+	if err := ts.DeleteQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("DeleteQuad: %v", err)
+	}
+
+	triples, graphs, err = ts.GetQuad("Alice", "knows", "Bob", "Graph1")
+	if err != nil {
+		t.Fatalf("GetQuad after DeleteQuad: %v", err)
+	}
+	if len(triples) != 0 || len(graphs) != 0 {
+		t.Fatalf("GetQuad after DeleteQuad returned %d triples / %d graphs, want 0/0", len(triples), len(graphs))
+	}
+}
+
+// TestQuadDefaultAndNamedGraphDontCollide checks that the same
+// (subject, predicate, object) in the default graph and in a named graph
+// are tracked as distinct quads, in distinct indexes.
+func TestQuadDefaultAndNamedGraphDontCollide(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.PutQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("PutQuad: %v", err)
+	}
+
+	if err := ts.DeleteQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("DeleteQuad: %v", err)
+	}
+
+	got, err := ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("default-graph triple was affected by DeleteQuad on the named graph: got %d, want 1", len(got))
+	}
+
+	triples, _, err := ts.GetQuad("Alice", "knows", "Bob", "Graph1")
+	if err != nil {
+		t.Fatalf("GetQuad: %v", err)
+	}
+	if len(triples) != 0 {
+		t.Fatalf("named-graph quad still present after DeleteQuad: got %d, want 0", len(triples))
+	}
+}
+
+// TestGetQuadNilGraphMatchesAcrossGraphs checks that GetQuad with a nil
+// graph finds a matching triple regardless of which graph (default or
+// named) it was stored in.
+func TestGetQuadNilGraphMatchesAcrossGraphs(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.PutQuad("Alice", "knows", "Carol", "Graph1"); err != nil {
+		t.Fatalf("PutQuad: %v", err)
+	}
+
+	triples, graphs, err := ts.GetQuad("Alice", "knows", nil, nil)
+	if err != nil {
+		t.Fatalf("GetQuad: %v", err)
+	}
+	if len(triples) != 2 || len(graphs) != 2 {
+		t.Fatalf("GetQuad(nil graph) returned %d triples / %d graphs, want 2/2", len(triples), len(graphs))
+	}
+
+	sawDefault, sawNamed := false, false
+	for _, g := range graphs {
+		if g == nil {
+			sawDefault = true
+		} else {
+			sawNamed = true
+		}
+	}
+	if !sawDefault || !sawNamed {
+		t.Fatalf("GetQuad(nil graph) graphs = %v, want one nil (default) and one non-nil (named)", graphs)
+	}
+}
+
+// TestDeleteQuadDoesNotGCSharedTerm checks that deleting a named-graph
+// quad doesn't garbage-collect a term still referenced by an equivalent
+// default-graph triple - the quad analogue of
+// TestDeleteDoesNotGCSharedTerms in database_test.go.
+func TestDeleteQuadDoesNotGCSharedTerm(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.PutQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("PutQuad: %v", err)
+	}
+
+	if err := ts.DeleteQuad("Alice", "knows", "Bob", "Graph1"); err != nil {
+		t.Fatalf("DeleteQuad: %v", err)
+	}
+
+	got, err := ts.Get("Alice", "knows", "Bob")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("(Alice, knows, Bob) was garbage-collected by DeleteQuad: Get returned %d, want 1", len(got))
+	}
+}
+
+func TestEachQuad(t *testing.T) {
+	ts := newTestStore(t)
+
+	if err := ts.Put("Alice", "knows", "Bob"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ts.PutQuad("Alice", "knows", "Carol", "Graph1"); err != nil {
+		t.Fatalf("PutQuad: %v", err)
+	}
+
+	type seen struct {
+		s, p, o string
+		graph   interface{}
+	}
+	var got []seen
+	err := ts.EachQuad(func(s, p, o, g interface{}) bool {
+		got = append(got, seen{s.(string), p.(string), o.(string), g})
+		return true
+	})
+	if err != nil {
+		t.Fatalf("EachQuad: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("EachQuad visited %d quads, want 2", len(got))
+	}
+
+	sawDefault, sawNamed := false, false
+	for _, s := range got {
+		if s.s != "Alice" || s.p != "knows" {
+			t.Fatalf("EachQuad visited unexpected quad %+v", s)
+		}
+		switch {
+		case s.o == "Bob" && s.graph == nil:
+			sawDefault = true
+		case s.o == "Carol" && s.graph == "Graph1":
+			sawNamed = true
+		}
+	}
+	if !sawDefault || !sawNamed {
+		t.Fatalf("EachQuad results = %+v, want the default-graph and named-graph quads", got)
+	}
+}
@@ -0,0 +1,393 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dgraph-io/badger"
+)
+
+// Pattern is a single (subject, predicate, object) graph pattern for
+// Query. Any position may be a bound term (anything Put accepts) or a
+// variable, written as a string starting with "?" (e.g. "?x"). The same
+// variable name may appear in more than one position, within a pattern or
+// across patterns, to require the matching terms be equal.
+type Pattern struct {
+	Subject   interface{}
+	Predicate interface{}
+	Object    interface{}
+}
+
+// estimateCap bounds how many keys the planner will count when estimating
+// an index range's size, so planning itself never turns into a full scan.
+const estimateCap = 1000
+
+// isVariable reports whether term is a Query variable, and if so its name.
+func isVariable(term interface{}) (string, bool) {
+	s, ok := term.(string)
+	if ok && strings.HasPrefix(s, "?") {
+		return s, true
+	}
+	return "", false
+}
+
+// Query executes patterns as a conjunctive (BGP-style) graph query and
+// streams variable bindings on the returned channel. Each binding maps
+// every variable used in patterns to its materialized term value. The
+// planner picks the cheapest available index (SPO/POS/SOP) for each
+// pattern based on which of its positions are already bound - either by a
+// literal term or by a variable a prior pattern in the join order
+// produces - and falls back to a hash join for the one case with no
+// usable index prefix: a pattern whose only bound position is the object.
+//
+// Query runs the join in a goroutine holding an open read transaction
+// until the returned channel is fully drained or ctx is done, whichever
+// comes first - a caller that stops reading early (e.g. after the first
+// match) must cancel ctx, or the goroutine and its transaction leak.
+func (t *Triplestore) Query(ctx context.Context, patterns []Pattern) (<-chan map[string]interface{}, error) {
+	txn := t.db.NewTransaction(false)
+
+	plan, err := t.planQuery(txn, patterns)
+	if err != nil {
+		txn.Discard()
+		return nil, err
+	}
+
+	out := make(chan map[string]interface{})
+	go func() {
+		defer txn.Discard()
+		defer close(out)
+
+		bindings := []map[string][]byte{{}}
+		for _, pp := range plan {
+			bindings = t.executePattern(txn, bindings, pp)
+			if len(bindings) == 0 {
+				return
+			}
+		}
+		for _, b := range bindings {
+			select {
+			case out <- t.materializeBinding(b):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (t *Triplestore) materializeBinding(b map[string][]byte) map[string]interface{} {
+	names := make([]string, 0, len(b))
+	ids := make([][]byte, 0, len(b))
+	for name, id := range b {
+		names = append(names, name)
+		ids = append(ids, id)
+	}
+	vals := t.Materialize(ids)
+	res := make(map[string]interface{}, len(b))
+	for i, name := range names {
+		res[name] = vals[i]
+	}
+	return res
+}
+
+// plannedPattern is a Pattern together with the index chosen to execute
+// it and which of its three positions are already bound at that point in
+// the join order.
+type plannedPattern struct {
+	pat      Pattern
+	bound    [3]bool // subject, predicate, object
+	index    string  // "SPO", "POS", "SOP", "HASH" or "SCAN"
+	variable [3]string
+}
+
+// planQuery greedily orders patterns by selectivity: at each step it picks
+// the remaining pattern with the most positions already bound (by a
+// literal, or by a variable an earlier pattern in the plan produces),
+// breaking ties using Badger key-range size estimates for each
+// candidate's index prefix.
+func (t *Triplestore) planQuery(txn *badger.Txn, patterns []Pattern) ([]plannedPattern, error) {
+	remaining := append([]Pattern{}, patterns...)
+	boundVars := map[string]bool{}
+	plan := make([]plannedPattern, 0, len(patterns))
+
+	for len(remaining) > 0 {
+		bestAt := 0
+		var best plannedPattern
+		bestBoundCount := -1
+		bestCost := -1
+
+		for i, pat := range remaining {
+			pp := planPattern(pat, boundVars)
+			cost, err := t.estimatePatternCost(txn, pp)
+			if err != nil {
+				return nil, err
+			}
+			boundCount := 0
+			for _, b := range pp.bound {
+				if b {
+					boundCount++
+				}
+			}
+			if boundCount > bestBoundCount || (boundCount == bestBoundCount && cost < bestCost) {
+				bestBoundCount, bestCost, best, bestAt = boundCount, cost, pp, i
+			}
+		}
+
+		plan = append(plan, best)
+		for _, name := range best.variable {
+			if name != "" {
+				boundVars[name] = true
+			}
+		}
+		remaining = append(remaining[:bestAt], remaining[bestAt+1:]...)
+	}
+	return plan, nil
+}
+
+// planPattern determines which positions of pat are bound given the
+// variables already produced earlier in the join order, and picks the
+// index that lets those bound positions be used as a Seek prefix.
+func planPattern(pat Pattern, boundVars map[string]bool) plannedPattern {
+	pp := plannedPattern{pat: pat}
+
+	terms := [3]interface{}{pat.Subject, pat.Predicate, pat.Object}
+	for i, term := range terms {
+		if name, ok := isVariable(term); ok {
+			pp.variable[i] = name
+			pp.bound[i] = boundVars[name]
+		} else {
+			pp.bound[i] = true
+		}
+	}
+
+	s, p, o := pp.bound[0], pp.bound[1], pp.bound[2]
+	switch {
+	case s && p:
+		pp.index = "SPO"
+	case p && o:
+		pp.index = "POS"
+	case s && o:
+		pp.index = "SOP"
+	case s:
+		pp.index = "SPO"
+	case p:
+		pp.index = "POS"
+	case o:
+		pp.index = "HASH" // object-only bound has no usable index prefix
+	default:
+		pp.index = "SCAN"
+	}
+	return pp
+}
+
+// estimatePatternCost estimates the selectivity of pp's chosen index
+// prefix using the literal (not yet-to-be-joined) bound terms, via a
+// capped key count - a stand-in for Badger range-size statistics.
+func (t *Triplestore) estimatePatternCost(txn *badger.Txn, pp plannedPattern) (int, error) {
+	if pp.index == "HASH" {
+		return t.estimateRangeSize(txn, dbSPO, estimateCap), nil
+	}
+
+	var prefixTerms [][]byte
+	order := indexOrder(pp.index)
+	terms := [3]interface{}{pp.pat.Subject, pp.pat.Predicate, pp.pat.Object}
+	for _, pos := range order {
+		if pp.variable[pos] != "" {
+			break
+		}
+		id, err := t.literalId(txn, terms[pos])
+		if err != nil {
+			return 0, err
+		}
+		if id == nil {
+			return 0, nil
+		}
+		prefixTerms = append(prefixTerms, id)
+	}
+
+	prefix := zcopy(append([][]byte{indexPrefix(pp.index)}, prefixTerms...)...)
+	return t.estimateRangeSize(txn, prefix, estimateCap), nil
+}
+
+func (t *Triplestore) literalId(txn *badger.Txn, term interface{}) ([]byte, error) {
+	if _, ok := isVariable(term); ok {
+		return nil, nil
+	}
+	m, err := t.marshal(term)
+	if err != nil {
+		return nil, err
+	}
+	return t.getIdIfExists(txn, m)
+}
+
+func (t *Triplestore) estimateRangeSize(txn *badger.Txn, prefix []byte, cap int) int {
+	it := txn.NewIterator(t.iopts)
+	defer it.Close()
+	n := 0
+	for it.Seek(prefix); it.ValidForPrefix(prefix) && n < cap; it.Next() {
+		n++
+	}
+	return n
+}
+
+// indexOrder returns the subject(0)/predicate(1)/object(2) positions in
+// the order they appear within the named index's key.
+func indexOrder(index string) [3]int {
+	switch index {
+	case "POS":
+		return [3]int{1, 2, 0}
+	case "SOP":
+		return [3]int{0, 2, 1}
+	default: // SPO, SCAN
+		return [3]int{0, 1, 2}
+	}
+}
+
+func indexPrefix(index string) []byte {
+	switch index {
+	case "POS":
+		return dbPOS
+	case "SOP":
+		return dbSOP
+	default:
+		return dbSPO
+	}
+}
+
+// executePattern extends bindings with pp's matches, either via a
+// nested-loop Seek per existing binding (when pp's index gives a usable
+// prefix) or, for the HASH plan, via a one-time hash join: the full SPO
+// relation is scanned once into a map keyed by object ID and probed for
+// every existing binding, rather than re-scanning per binding.
+func (t *Triplestore) executePattern(txn *badger.Txn, bindings []map[string][]byte, pp plannedPattern) []map[string][]byte {
+	if pp.index == "HASH" {
+		return t.executeHashJoin(txn, bindings, pp)
+	}
+
+	out := make([]map[string][]byte, 0, len(bindings))
+	for _, b := range bindings {
+		out = append(out, t.executeNestedLoop(txn, b, pp)...)
+	}
+	return out
+}
+
+func (t *Triplestore) executeNestedLoop(txn *badger.Txn, b map[string][]byte, pp plannedPattern) []map[string][]byte {
+	terms := [3]interface{}{pp.pat.Subject, pp.pat.Predicate, pp.pat.Object}
+	order := indexOrder(pp.index)
+
+	var prefixParts [][]byte
+	for _, pos := range order {
+		if pp.variable[pos] != "" && b[pp.variable[pos]] == nil {
+			break
+		}
+		id, ok := t.resolvedId(txn, b, terms[pos], pp.variable[pos])
+		if !ok {
+			return nil
+		}
+		prefixParts = append(prefixParts, id)
+	}
+	prefix := zcopy(append([][]byte{indexPrefix(pp.index)}, prefixParts...)...)
+
+	var out []map[string][]byte
+	it := txn.NewIterator(t.iopts)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		key := []byte(it.Item().Key())
+		rest := key[1:]
+		if len(rest) != 27 {
+			continue
+		}
+		// Copied, not sliced: rest aliases the iterator's own key buffer,
+		// which Badger is free to overwrite on the next it.Next(), but
+		// row is kept in nb/out well past that point.
+		vals := [3][]byte{zcopy(rest[0:9]), zcopy(rest[9:18]), zcopy(rest[18:27])} // in index order
+		row := [3][]byte{}
+		for i, pos := range order {
+			row[pos] = vals[i]
+		}
+		if nb, ok := extendBinding(b, pp, row); ok {
+			out = append(out, nb)
+		}
+	}
+	return out
+}
+
+// resolvedId resolves a pattern position to its interned ID, from the
+// current binding if it's a variable, otherwise by interning the literal.
+func (t *Triplestore) resolvedId(txn *badger.Txn, b map[string][]byte, term interface{}, variable string) ([]byte, bool) {
+	if variable != "" {
+		id, ok := b[variable]
+		return id, ok
+	}
+	m, err := t.marshal(term)
+	if err != nil {
+		return nil, false
+	}
+	id, err := t.getIdIfExists(txn, m)
+	if err != nil {
+		return nil, false
+	}
+	return id, id != nil
+}
+
+// extendBinding checks row against b's existing bindings (so a repeated
+// variable is required to match) and returns the extended binding.
+func extendBinding(b map[string][]byte, pp plannedPattern, row [3][]byte) (map[string][]byte, bool) {
+	nb := make(map[string][]byte, len(b)+3)
+	for k, v := range b {
+		nb[k] = v
+	}
+	for i, name := range pp.variable {
+		if name == "" {
+			continue
+		}
+		if existing, ok := nb[name]; ok {
+			if !bytesEqual(existing, row[i]) {
+				return nil, false
+			}
+			continue
+		}
+		nb[name] = row[i]
+	}
+	return nb, true
+}
+
+func (t *Triplestore) executeHashJoin(txn *badger.Txn, bindings []map[string][]byte, pp plannedPattern) []map[string][]byte {
+	// Build side: scan the whole SPO relation once, hashed by object ID.
+	hash := map[string][][2][]byte{}
+	it := txn.NewIterator(t.iopts)
+	for it.Seek(dbSPO); it.ValidForPrefix(dbSPO); it.Next() {
+		key := []byte(it.Item().Key())
+		rest := key[1:]
+		if len(rest) != 27 {
+			continue
+		}
+		// Copied for the same reason as executeNestedLoop: hash outlives
+		// this iteration, but rest aliases the iterator's recycled key
+		// buffer.
+		si, pi, oi := zcopy(rest[0:9]), zcopy(rest[9:18]), zcopy(rest[18:27])
+		k := string(oi)
+		hash[k] = append(hash[k], [2][]byte{si, pi})
+	}
+	it.Close()
+
+	var out []map[string][]byte
+	for _, b := range bindings {
+		oid, ok := t.resolvedId(txn, b, pp.pat.Object, pp.variable[2])
+		if !ok {
+			continue
+		}
+		for _, sp := range hash[string(oid)] {
+			row := [3][]byte{sp[0], sp[1], oid}
+			if nb, ok := extendBinding(b, pp, row); ok {
+				out = append(out, nb)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	ts := newTestStore(t)
+
+	for _, triple := range [][3]string{
+		{"Alice", "knows", "Bob"},
+		{"Alice", "knows", "Carol"},
+		{"Bob", "knows", "Carol"},
+	} {
+		if err := ts.Put(triple[0], triple[1], triple[2]); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out, err := ts.Query(ctx, []Pattern{{Subject: "Alice", Predicate: "knows", Object: "?who"}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	got := map[interface{}]bool{}
+	for b := range out {
+		got[b["?who"]] = true
+	}
+	if len(got) != 2 || !got["Bob"] || !got["Carol"] {
+		t.Fatalf("Query results = %v, want {Bob, Carol}", got)
+	}
+}
+
+// TestQueryEarlyBreakDoesNotLeak checks that canceling ctx after reading
+// only the first binding lets Query's goroutine (and the read
+// transaction it holds) exit, rather than blocking forever on a send to
+// an unread channel.
+func TestQueryEarlyBreakDoesNotLeak(t *testing.T) {
+	ts := newTestStore(t)
+
+	for i := 0; i < 10; i++ {
+		if err := ts.Put("Alice", "knows", string(rune('A'+i))); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, err := ts.Query(ctx, []Pattern{{Subject: "Alice", Predicate: "knows", Object: "?who"}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	<-out
+	cancel()
+
+	// With ctx canceled and nothing receiving, ctx.Done() is the only
+	// ready case in the goroutine's select, so it must close out on its
+	// own. A goroutine that ignored ctx would instead still be blocked
+	// sending the next binding.
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("Query sent another binding after ctx was canceled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Query's goroutine did not exit after ctx was canceled")
+	}
+}
+
+// TestQueryManyRowsSinglePattern drains enough rows from a single
+// nested-loop pattern to catch executeNestedLoop retaining an ID slice
+// that aliases the scanning iterator's recycled key buffer instead of a
+// copy of it - with only a couple of rows, the aliasing bug is invisible
+// because nothing overwrites the buffer before the results are read.
+func TestQueryManyRowsSinglePattern(t *testing.T) {
+	ts := newTestStore(t)
+
+	const n = 50
+	want := map[string]bool{}
+	for i := 0; i < n; i++ {
+		obj := fmt.Sprintf("Obj%03d", i)
+		if err := ts.Put("Alice", "knows", obj); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		want[obj] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, err := ts.Query(ctx, []Pattern{{Subject: "Alice", Predicate: "knows", Object: "?who"}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	got := map[string]bool{}
+	for b := range out {
+		got[b["?who"].(string)] = true
+	}
+	if len(got) != n {
+		t.Fatalf("Query returned %d distinct bindings, want %d (got %v)", len(got), n, got)
+	}
+	for obj := range want {
+		if !got[obj] {
+			t.Fatalf("Query result missing %q - binding IDs were likely aliased/corrupted", obj)
+		}
+	}
+}
+
+// TestQueryMultiPatternJoin joins two patterns on a shared variable over
+// enough rows to exercise extendBinding's equality check against IDs
+// retained from earlier iterations of executeNestedLoop's Seek loop.
+func TestQueryMultiPatternJoin(t *testing.T) {
+	ts := newTestStore(t)
+
+	const n = 30
+	for i := 0; i < n; i++ {
+		person := fmt.Sprintf("Person%03d", i)
+		if err := ts.Put("Alice", "knows", person); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if i%3 == 0 {
+			if err := ts.Put(person, "knows", "Zed"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, err := ts.Query(ctx, []Pattern{
+		{Subject: "Alice", Predicate: "knows", Object: "?who"},
+		{Subject: "?who", Predicate: "knows", Object: "Zed"},
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	got := map[string]bool{}
+	for b := range out {
+		got[b["?who"].(string)] = true
+	}
+	wantCount := 0
+	for i := 0; i < n; i++ {
+		if i%3 == 0 {
+			wantCount++
+		}
+	}
+	if len(got) != wantCount {
+		t.Fatalf("join returned %d bindings, want %d (got %v)", len(got), wantCount, got)
+	}
+	for i := 0; i < n; i++ {
+		if i%3 != 0 {
+			continue
+		}
+		person := fmt.Sprintf("Person%03d", i)
+		if !got[person] {
+			t.Fatalf("join result missing %q - an earlier pattern's binding was likely aliased/corrupted", person)
+		}
+	}
+}
+
+// TestQueryHashJoin forces the HASH plan (the only pattern position
+// bound is the object, which has no usable index prefix) over enough
+// rows to exercise executeHashJoin retaining subject/predicate IDs from
+// earlier iterations of its build-side scan.
+func TestQueryHashJoin(t *testing.T) {
+	ts := newTestStore(t)
+
+	const n = 50
+	want := map[string]bool{}
+	for i := 0; i < n; i++ {
+		subj := fmt.Sprintf("Person%03d", i)
+		pred := fmt.Sprintf("pred%03d", i)
+		if err := ts.Put(subj, pred, "Zed"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		want[subj] = true
+	}
+	// An unrelated row with "Zed" as its subject rather than its object,
+	// so the build side holds a second object key the probe must not
+	// match.
+	if err := ts.Put("Zed", "knows", "Someone"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Neither subject nor predicate is bound, so only the object
+	// position is - the one shape that forces the HASH plan.
+	out, err := ts.Query(ctx, []Pattern{{Subject: "?who", Predicate: "?pred", Object: "Zed"}})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	got := map[string]bool{}
+	for b := range out {
+		got[b["?who"].(string)] = true
+	}
+	if len(got) != n {
+		t.Fatalf("hash join returned %d bindings, want %d (got %v)", len(got), n, got)
+	}
+	for subj := range want {
+		if !got[subj] {
+			t.Fatalf("hash join result missing %q - IDs were likely aliased/corrupted", subj)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// replicationPollInterval is how often Serve checks the leader store for
+// writes made since the last round.
+const replicationPollInterval = 500 * time.Millisecond
+
+// maxFrameSize bounds the length header readFrame will honor, so a
+// corrupt or hostile peer can't make it allocate an unbounded buffer.
+const maxFrameSize = 1 << 30 // 1 GiB
+
+// Serve ships src to conn for a peer's Replicate call: first src's full
+// contents (since = 0), then a new Backup of whatever changed since the
+// previous round, repeated until ctx is done or conn errors. Each round
+// is sent as a length-prefixed frame so Replicate knows exactly where one
+// Backup stream ends and the next begins - Badger's backup format has no
+// end-of-stream marker of its own when read off a connection that stays
+// open for the next round.
+func Serve(ctx context.Context, conn net.Conn, src *Triplestore) error {
+	since := uint64(0)
+	ticker := time.NewTicker(replicationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var buf bytes.Buffer
+		next, err := src.Backup(&buf, since)
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(conn, buf.Bytes()); err != nil {
+			return err
+		}
+		since = next
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Replicate reads the frames Serve writes to conn and Restores each one
+// into dst, so dst becomes - and, as later rounds arrive, stays - a
+// warm-standby replica of the remote Triplestore. It returns when conn is
+// closed or ctx is done.
+func Replicate(ctx context.Context, conn net.Conn, dst *Triplestore) error {
+	closed := make(chan struct{})
+	defer close(closed)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-closed:
+		}
+	}()
+
+	for {
+		payload, err := readFrame(conn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := dst.Restore(bytes.NewReader(payload)); err != nil {
+			return err
+		}
+	}
+}
+
+func writeFrame(w io.Writer, payload []byte) error {
+	var hdr [8]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(len(payload)))
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint64(hdr[:])
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("database: replication frame of %d bytes exceeds %d byte limit", n, maxFrameSize)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
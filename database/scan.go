@@ -0,0 +1,159 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger"
+)
+
+// ScanAction tells a Scan* callback's driving loop what to do next.
+type ScanAction int
+
+const (
+	// ScanContinue advances to the next key in index order.
+	ScanContinue ScanAction = iota
+	// ScanStop ends the scan immediately.
+	ScanStop
+	// ScanSkipSubject seeks past every remaining key sharing the current
+	// subject, instead of stepping through them one at a time.
+	ScanSkipSubject
+	// ScanSkipPredicate is the predicate analogue of ScanSkipSubject.
+	ScanSkipPredicate
+	// ScanSkipObject is the object analogue of ScanSkipSubject.
+	ScanSkipObject
+)
+
+// ScanSPO streams every (subject, predicate, object) triple whose terms
+// begin with sPrefix, pPrefix and oPrefix respectively - pass nil for a
+// prefix to leave that position unconstrained - over the SPO index,
+// calling fn for each. It opens one iterator for the whole scan and
+// always closes it before returning, unlike the channel-and-goroutine
+// dance in Get/get, which leaks iterators (stacked defers inside
+// a loop) and could leave a goroutine blocked writing to an abandoned
+// channel if the caller stopped consuming early.
+func (t *Triplestore) ScanSPO(sPrefix []byte, pPrefix []byte, oPrefix []byte, fn func(s, p, o []byte) ScanAction) error {
+	return t.scan("SPO", [3][]byte{sPrefix, pPrefix, oPrefix}, fn)
+}
+
+// ScanPOS is the POS-index analogue of ScanSPO.
+func (t *Triplestore) ScanPOS(pPrefix []byte, oPrefix []byte, sPrefix []byte, fn func(s, p, o []byte) ScanAction) error {
+	return t.scan("POS", [3][]byte{pPrefix, oPrefix, sPrefix}, fn)
+}
+
+// ScanSOP is the SOP-index analogue of ScanSPO.
+func (t *Triplestore) ScanSOP(sPrefix []byte, oPrefix []byte, pPrefix []byte, fn func(s, p, o []byte) ScanAction) error {
+	return t.scan("SOP", [3][]byte{sPrefix, oPrefix, pPrefix}, fn)
+}
+
+// scan drives the shared Seek/Next loop for the Scan* methods. comps is
+// the 3 prefix constraints in the named index's own physical key order;
+// fn is always called with (subject, predicate, object) regardless of
+// index.
+func (t *Triplestore) scan(index string, comps [3][]byte, fn func(s, p, o []byte) ScanAction) error {
+	idxPrefix := indexPrefix(index)
+	order := indexOrder(index)
+
+	var prefix []byte
+	for _, c := range comps {
+		if c == nil {
+			break
+		}
+		prefix = zcopy(prefix, c)
+		if len(c) != 9 {
+			break
+		}
+	}
+	prefix = zcopy(idxPrefix, prefix)
+
+	return t.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(t.iopts)
+		defer it.Close()
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); {
+			key := []byte(it.Item().Key())
+			rest := key[len(idxPrefix):]
+			if len(rest) != 27 {
+				it.Next()
+				continue
+			}
+			comp := [3][]byte{rest[0:9], rest[9:18], rest[18:27]}
+			// Constraints past the seek prefix are checked per key.
+			matched := true
+			for i, c := range comps {
+				if c != nil && !bytes.HasPrefix(comp[i], c) {
+					matched = false
+				}
+			}
+			if !matched {
+				it.Next()
+				continue
+			}
+			var row [3][]byte
+			for i, pos := range order {
+				row[pos] = comp[i]
+			}
+
+			switch action := fn(row[0], row[1], row[2]); action {
+			case ScanStop:
+				return nil
+			case ScanSkipSubject:
+				seekPastField(it, key, idxPrefix, order, 0)
+			case ScanSkipPredicate:
+				seekPastField(it, key, idxPrefix, order, 1)
+			case ScanSkipObject:
+				seekPastField(it, key, idxPrefix, order, 2)
+			default:
+				it.Next()
+			}
+		}
+		return nil
+	})
+}
+
+// seekPastField seeks it to the smallest key strictly greater than every
+// key sharing key's bytes through the end of the named field's component
+// (field: 0 = subject, 1 = predicate, 2 = object), letting callers skip
+// past a run of duplicates - e.g. for DISTINCT, or a merge-join - without
+// stepping through them one Next() at a time.
+func seekPastField(it *badger.Iterator, key []byte, idxPrefix []byte, order [3]int, field int) {
+	componentIndex := 0
+	for i, pos := range order {
+		if pos == field {
+			componentIndex = i
+			break
+		}
+	}
+	end := len(idxPrefix) + 9*(componentIndex+1)
+	if end > len(key) {
+		end = len(key)
+	}
+
+	next := seekPastPrefix(key[:end])
+	if next == nil {
+		// key[:end] is already the maximum possible value; nothing left.
+		it.Seek(append(key[:end:end], 0xFF))
+		return
+	}
+	it.Seek(next)
+}
+
+// seekPastPrefix returns the smallest byte slice strictly greater than
+// every slice sharing prefix as a leading substring, by incrementing
+// prefix as a big big-endian integer (with carry). It returns nil if
+// prefix is already all 0xFF bytes.
+func seekPastPrefix(prefix []byte) []byte {
+	next := make([]byte, len(prefix))
+	copy(next, prefix)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i] < 0xFF {
+			next[i]++
+			return next[:i+1]
+		}
+		next[i] = 0
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package database
+
+import "testing"
+
+func TestScanSPO(t *testing.T) {
+	ts := newTestStore(t)
+
+	triples := [][3]string{
+		{"Alice", "knows", "Bob"},
+		{"Alice", "knows", "Carol"},
+		{"Bob", "knows", "Carol"},
+	}
+	for _, tr := range triples {
+		if err := ts.Put(tr[0], tr[1], tr[2]); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	var got [][3]string
+	err := ts.ScanSPO(nil, nil, nil, func(s, p, o []byte) ScanAction {
+		vals := ts.Materialize([][]byte{s, p, o})
+		got = append(got, [3]string{vals[0].(string), vals[1].(string), vals[2].(string)})
+		return ScanContinue
+	})
+	if err != nil {
+		t.Fatalf("ScanSPO: %v", err)
+	}
+	if len(got) != len(triples) {
+		t.Fatalf("ScanSPO visited %d triples, want %d", len(got), len(triples))
+	}
+}
+
+// TestScanSPOStop checks that returning ScanStop ends the scan after the
+// first callback instead of visiting every matching triple.
+func TestScanSPOStop(t *testing.T) {
+	ts := newTestStore(t)
+
+	for _, tr := range [][3]string{
+		{"Alice", "knows", "Bob"},
+		{"Alice", "knows", "Carol"},
+	} {
+		if err := ts.Put(tr[0], tr[1], tr[2]); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	calls := 0
+	err := ts.ScanSPO(nil, nil, nil, func(s, p, o []byte) ScanAction {
+		calls++
+		return ScanStop
+	})
+	if err != nil {
+		t.Fatalf("ScanSPO: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("ScanSPO called fn %d times after ScanStop, want 1", calls)
+	}
+}
+
+// TestScanSPOSkipSubject checks that ScanSkipSubject skips straight past
+// every remaining key sharing the current subject instead of visiting
+// them one at a time.
+func TestScanSPOSkipSubject(t *testing.T) {
+	ts := newTestStore(t)
+
+	for _, tr := range [][3]string{
+		{"Alice", "knows", "Bob"},
+		{"Alice", "knows", "Carol"},
+		{"Alice", "knows", "Dave"},
+		{"Bob", "knows", "Carol"},
+	} {
+		if err := ts.Put(tr[0], tr[1], tr[2]); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	var subjects []string
+	err := ts.ScanSPO(nil, nil, nil, func(s, p, o []byte) ScanAction {
+		vals := ts.Materialize([][]byte{s})
+		subjects = append(subjects, vals[0].(string))
+		return ScanSkipSubject
+	})
+	if err != nil {
+		t.Fatalf("ScanSPO: %v", err)
+	}
+	if len(subjects) != 2 || subjects[0] != "Alice" || subjects[1] != "Bob" {
+		t.Fatalf("ScanSPO with ScanSkipSubject visited %v, want one callback per distinct subject", subjects)
+	}
+}
@@ -0,0 +1,248 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/crsdrw/radv/database"
+)
+
+const rdfType = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+
+// LoadJSONLD reads a flattened, expanded-form JSON-LD document (a JSON
+// array of node objects using "@id"/"@type" and IRI-keyed properties) from
+// r and writes its triples into ts. It does not perform context-based
+// expansion; documents must already be in expanded form. It returns the
+// number of triples written.
+func LoadJSONLD(ts *database.Triplestore, r io.Reader, opts ...BatchOption) (int64, error) {
+	o := newLoadOptions(opts)
+
+	var nodes []map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&nodes); err != nil {
+		return 0, fmt.Errorf("rdfio: json-ld: %w", err)
+	}
+
+	batch := make([][3]interface{}, 0, o.batchSize)
+	var total int64
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := ts.PutAll(batch, o.batchSize)
+		total += n
+		batch = batch[:0]
+		return err
+	}
+
+	for _, node := range nodes {
+		subject, err := jsonldSubject(node)
+		if err != nil {
+			return total, err
+		}
+		for key, val := range node {
+			if key == "@id" {
+				continue
+			}
+			if key == "@type" {
+				for _, t := range jsonldStrings(val) {
+					batch = append(batch, [3]interface{}{subject, NewIRI(rdfType), NewIRI(t)})
+				}
+				continue
+			}
+			objs, err := jsonldObjects(val)
+			if err != nil {
+				return total, err
+			}
+			for _, obj := range objs {
+				batch = append(batch, [3]interface{}{subject, NewIRI(key), obj})
+			}
+		}
+		if len(batch) >= o.batchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, flush()
+}
+
+func jsonldSubject(node map[string]interface{}) (Term, error) {
+	id, ok := node["@id"]
+	if !ok {
+		return Term{}, fmt.Errorf("rdfio: json-ld: node missing \"@id\"")
+	}
+	s, ok := id.(string)
+	if !ok {
+		return Term{}, fmt.Errorf("rdfio: json-ld: \"@id\" must be a string")
+	}
+	if blank, ok := blankLabel(s); ok {
+		return NewBlankNode(blank), nil
+	}
+	return NewIRI(s), nil
+}
+
+func jsonldStrings(val interface{}) []string {
+	switch v := val.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var out []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func jsonldObjects(val interface{}) ([]Term, error) {
+	items, ok := val.([]interface{})
+	if !ok {
+		items = []interface{}{val}
+	}
+	var terms []Term
+	for _, item := range items {
+		t, err := jsonldObject(item)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+func jsonldObject(item interface{}) (Term, error) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return Term{}, fmt.Errorf("rdfio: json-ld: expected value object, got %T", item)
+	}
+	if id, ok := m["@id"].(string); ok {
+		if blank, ok := blankLabel(id); ok {
+			return NewBlankNode(blank), nil
+		}
+		return NewIRI(id), nil
+	}
+	value, ok := m["@value"]
+	if !ok {
+		return Term{}, fmt.Errorf("rdfio: json-ld: value object missing \"@value\" or \"@id\"")
+	}
+	lexical := fmt.Sprintf("%v", value)
+	if lang, ok := m["@language"].(string); ok {
+		return NewLangLiteral(lexical, lang), nil
+	}
+	if dt, ok := m["@type"].(string); ok {
+		return NewTypedLiteral(lexical, dt), nil
+	}
+	return NewLiteral(lexical), nil
+}
+
+func blankLabel(id string) (string, bool) {
+	if len(id) > 2 && id[0:2] == "_:" {
+		return id[2:], true
+	}
+	return "", false
+}
+
+// DumpJSONLD writes every triple in ts's default graph to w as a
+// flattened, expanded-form JSON-LD node array, grouping consecutive
+// triples sharing a subject (Each yields them in SPO order) into one node
+// object at a time rather than materializing the whole graph.
+func DumpJSONLD(ts *database.Triplestore, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprint(bw, "[\n"); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(bw)
+	var curSubject Term
+	var curNode map[string]interface{}
+	first := true
+
+	flush := func() error {
+		if curNode == nil {
+			return nil
+		}
+		if !first {
+			if _, err := fmt.Fprint(bw, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		return enc.Encode(curNode)
+	}
+
+	var werr error
+	err := ts.Each(func(s, p, o interface{}) bool {
+		st, pt, ot := toTerm(s), toTerm(p), toTerm(o)
+		if curNode == nil || st != curSubject {
+			if werr = flush(); werr != nil {
+				return false
+			}
+			curSubject = st
+			curNode = jsonldNewNode(st)
+		}
+		jsonldAddProperty(curNode, pt, ot)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(bw, "]\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func jsonldNewNode(s Term) map[string]interface{} {
+	node := map[string]interface{}{}
+	if s.Kind == BlankNode {
+		node["@id"] = "_:" + s.Value
+	} else {
+		node["@id"] = s.Value
+	}
+	return node
+}
+
+func jsonldAddProperty(node map[string]interface{}, p Term, o Term) {
+	if p.Kind == IRI && p.Value == rdfType {
+		types, _ := node["@type"].([]string)
+		node["@type"] = append(types, o.Value)
+		return
+	}
+
+	key := p.Value
+	vals, _ := node[key].([]map[string]interface{})
+	node[key] = append(vals, jsonldValueObject(o))
+}
+
+func jsonldValueObject(o Term) map[string]interface{} {
+	switch o.Kind {
+	case IRI:
+		return map[string]interface{}{"@id": o.Value}
+	case BlankNode:
+		return map[string]interface{}{"@id": "_:" + o.Value}
+	default:
+		v := map[string]interface{}{"@value": o.Value}
+		if o.Lang != "" {
+			v["@language"] = o.Lang
+		} else if o.Datatype != "" {
+			v["@type"] = o.Datatype
+		}
+		return v
+	}
+}
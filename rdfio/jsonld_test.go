@@ -0,0 +1,64 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/crsdrw/radv/database"
+)
+
+func TestJSONLDLoadDumpRoundTrip(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `[
+		{
+			"@id": "http://example.org/alice",
+			"@type": "http://example.org/Person",
+			"http://example.org/name": [{"@value": "Alice"}],
+			"http://example.org/knows": [{"@id": "http://example.org/bob"}]
+		}
+	]`
+	n, err := LoadJSONLD(ts, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LoadJSONLD: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("LoadJSONLD loaded %d triples, want 3", n)
+	}
+
+	got, err := ts.Get(NewIRI("http://example.org/alice"), NewIRI("http://example.org/knows"), NewIRI("http://example.org/bob"))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("Get(alice, knows, bob) = %d, want 1", len(got))
+	}
+
+	var buf bytes.Buffer
+	if err := DumpJSONLD(ts, &buf); err != nil {
+		t.Fatalf("DumpJSONLD: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"@id":"http://example.org/alice"`) {
+		t.Fatalf("dump missing alice's node, got:\n%s", out)
+	}
+	if !strings.Contains(out, `"http://example.org/Person"`) {
+		t.Fatalf("dump missing alice's @type, got:\n%s", out)
+	}
+}
+
+func TestJSONLDLoadMissingIDErrors(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `[{"http://example.org/name": [{"@value": "Alice"}]}]`
+	if _, err := LoadJSONLD(ts, strings.NewReader(doc)); err == nil {
+		t.Fatal("LoadJSONLD succeeded on a node missing \"@id\", want an error")
+	}
+}
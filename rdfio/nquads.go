@@ -0,0 +1,118 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/crsdrw/radv/database"
+)
+
+// LoadNQuads reads N-Quads statements from r and writes them into ts,
+// batching writes per opts' batch size. A statement with no graph term
+// is written to the default graph. It returns the number of quads
+// written.
+func LoadNQuads(ts *database.Triplestore, r io.Reader, opts ...BatchOption) (int64, error) {
+	o := newLoadOptions(opts)
+	batch := make([][4]interface{}, 0, o.batchSize)
+	var total int64
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := ts.PutQuadAll(batch, o.batchSize)
+		total += n
+		batch = batch[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		q, err := parseQuadLine(line)
+		if err != nil {
+			return total, fmt.Errorf("rdfio: n-quads line %d: %w", lineNo, err)
+		}
+		var graph interface{}
+		if !q.IsDefaultGraph() {
+			graph = q.Graph
+		}
+		batch = append(batch, [4]interface{}{q.Subject, q.Predicate, q.Object, graph})
+		if len(batch) >= o.batchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
+
+// DumpNQuads writes every quad in ts (default graph and every named
+// graph) to w as N-Quads, streaming one quad at a time.
+func DumpNQuads(ts *database.Triplestore, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var werr error
+	err := ts.EachQuad(func(s, p, o, g interface{}) bool {
+		st, pt, ot := toTerm(s), toTerm(p), toTerm(o)
+		if g == nil {
+			_, werr = fmt.Fprintf(bw, "%s %s %s .\n", st, pt, ot)
+		} else {
+			_, werr = fmt.Fprintf(bw, "%s %s %s %s .\n", st, pt, ot, toTerm(g))
+		}
+		return werr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	return bw.Flush()
+}
+
+func parseQuadLine(line string) (Quad, error) {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ".")
+	toks, err := tokenizeStatement(line)
+	if err != nil {
+		return Quad{}, err
+	}
+	if len(toks) != 3 && len(toks) != 4 {
+		return Quad{}, fmt.Errorf("expected subject predicate object [graph], got %d terms", len(toks))
+	}
+	s, err := parseTerm(toks[0])
+	if err != nil {
+		return Quad{}, err
+	}
+	p, err := parseTerm(toks[1])
+	if err != nil {
+		return Quad{}, err
+	}
+	o, err := parseTerm(toks[2])
+	if err != nil {
+		return Quad{}, err
+	}
+	q := Quad{Triple: Triple{Subject: s, Predicate: p, Object: o}}
+	if len(toks) == 4 {
+		g, err := parseTerm(toks[3])
+		if err != nil {
+			return Quad{}, err
+		}
+		q.Graph = g
+	}
+	return q, nil
+}
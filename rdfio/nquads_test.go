@@ -0,0 +1,51 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/crsdrw/radv/database"
+)
+
+func TestNQuadsLoadDumpRoundTrip(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob> .
+<http://example.org/alice> <http://example.org/knows> <http://example.org/carol> <http://example.org/Graph1> .
+`
+	n, err := LoadNQuads(ts, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LoadNQuads: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("LoadNQuads loaded %d quads, want 2", n)
+	}
+
+	var buf bytes.Buffer
+	if err := DumpNQuads(ts, &buf); err != nil {
+		t.Fatalf("DumpNQuads: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<http://example.org/alice> <http://example.org/knows> <http://example.org/bob> .") {
+		t.Fatalf("dump missing the default-graph quad, got:\n%s", out)
+	}
+	if !strings.Contains(out, "<http://example.org/alice> <http://example.org/knows> <http://example.org/carol> <http://example.org/Graph1> .") {
+		t.Fatalf("dump missing the named-graph quad, got:\n%s", out)
+	}
+}
+
+func TestNQuadsLoadMalformedLineErrors(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob .`
+	if _, err := LoadNQuads(ts, strings.NewReader(doc)); err == nil {
+		t.Fatal("LoadNQuads succeeded on an unterminated IRI, want an error")
+	}
+}
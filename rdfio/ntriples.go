@@ -0,0 +1,275 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/crsdrw/radv/database"
+)
+
+// defaultBatchSize is used by the Load* functions when no BatchOption is
+// given.
+const defaultBatchSize = 1000
+
+// BatchOption configures the bulk-import batch size used by the Load*
+// functions.
+type BatchOption func(*loadOptions)
+
+type loadOptions struct {
+	batchSize int
+}
+
+// WithBatchSize overrides the number of statements grouped into a single
+// Triplestore transaction during import.
+func WithBatchSize(n int) BatchOption {
+	return func(o *loadOptions) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+func newLoadOptions(opts []BatchOption) loadOptions {
+	o := loadOptions{batchSize: defaultBatchSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// LoadNTriples reads N-Triples statements from r and writes them into ts
+// as triples in the default graph, batching writes per opts' batch size.
+// It returns the number of triples written.
+func LoadNTriples(ts *database.Triplestore, r io.Reader, opts ...BatchOption) (int64, error) {
+	o := newLoadOptions(opts)
+	batch := make([][3]interface{}, 0, o.batchSize)
+	var total int64
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := ts.PutAll(batch, o.batchSize)
+		total += n
+		batch = batch[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tr, err := parseTripleLine(line)
+		if err != nil {
+			return total, fmt.Errorf("rdfio: n-triples line %d: %w", lineNo, err)
+		}
+		batch = append(batch, [3]interface{}{tr.Subject, tr.Predicate, tr.Object})
+		if len(batch) >= o.batchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
+
+// DumpNTriples writes every triple in ts's default graph to w as
+// N-Triples, streaming one triple at a time so the whole graph is never
+// materialized in memory.
+func DumpNTriples(ts *database.Triplestore, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var werr error
+	err := ts.Each(func(s, p, o interface{}) bool {
+		st, pt, ot := toTerm(s), toTerm(p), toTerm(o)
+		_, werr = fmt.Fprintf(bw, "%s %s %s .\n", st, pt, ot)
+		return werr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	return bw.Flush()
+}
+
+// toTerm coerces a materialized Triplestore value back into a Term. Values
+// put via the rdfio loaders round-trip as Term; anything else (e.g. a
+// plain Go value stored by a caller not using rdfio) is treated as a
+// plain literal of its default string form.
+func toTerm(v interface{}) Term {
+	if t, ok := v.(Term); ok {
+		return t
+	}
+	return NewLiteral(fmt.Sprintf("%v", v))
+}
+
+// parseTripleLine parses a single N-Triples statement line into a Triple,
+// stripping the line's trailing "." itself.
+func parseTripleLine(line string) (Triple, error) {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ".")
+	toks, err := tokenizeStatement(line)
+	if err != nil {
+		return Triple{}, err
+	}
+	if len(toks) != 3 {
+		return Triple{}, fmt.Errorf("expected subject predicate object, got %d terms", len(toks))
+	}
+	s, err := parseTerm(toks[0])
+	if err != nil {
+		return Triple{}, err
+	}
+	p, err := parseTerm(toks[1])
+	if err != nil {
+		return Triple{}, err
+	}
+	o, err := parseTerm(toks[2])
+	if err != nil {
+		return Triple{}, err
+	}
+	return Triple{Subject: s, Predicate: p, Object: o}, nil
+}
+
+// tokenizeStatement splits an N-Triples/N-Quads statement body into its
+// term tokens, respecting quoted literal strings and IRI angle brackets.
+func tokenizeStatement(line string) ([]string, error) {
+	var toks []string
+	i := 0
+	n := len(line)
+	for i < n {
+		for i < n && isSpace(line[i]) {
+			i++
+		}
+		if i >= n {
+			break
+		}
+		start := i
+		switch line[i] {
+		case '<':
+			end := strings.IndexByte(line[i:], '>')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated IRI in %q", line)
+			}
+			i += end + 1
+		case '"':
+			i++
+			for i < n {
+				if line[i] == '\\' {
+					i += 2
+					continue
+				}
+				if line[i] == '"' {
+					i++
+					break
+				}
+				i++
+			}
+			// consume an optional @lang or ^^<datatype> suffix
+			if i < n && line[i] == '@' {
+				i++
+				for i < n && !isSpace(line[i]) {
+					i++
+				}
+			} else if i+1 < n && line[i] == '^' && line[i+1] == '^' {
+				i += 2
+				if i < n && line[i] == '<' {
+					end := strings.IndexByte(line[i:], '>')
+					if end < 0 {
+						return nil, fmt.Errorf("unterminated datatype IRI in %q", line)
+					}
+					i += end + 1
+				}
+			}
+		default:
+			for i < n && !isSpace(line[i]) {
+				i++
+			}
+		}
+		toks = append(toks, line[start:i])
+	}
+	return toks, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+// parseTerm parses a single N-Triples/N-Quads term token (an IRI, blank
+// node, or literal) into a Term.
+func parseTerm(tok string) (Term, error) {
+	switch {
+	case strings.HasPrefix(tok, "<"):
+		if !strings.HasSuffix(tok, ">") {
+			return Term{}, fmt.Errorf("malformed IRI %q", tok)
+		}
+		return NewIRI(unescapeIRI(tok[1 : len(tok)-1])), nil
+	case strings.HasPrefix(tok, "_:"):
+		return NewBlankNode(tok[2:]), nil
+	case strings.HasPrefix(tok, "\""):
+		return parseLiteralTerm(tok)
+	default:
+		return Term{}, fmt.Errorf("unrecognized term %q", tok)
+	}
+}
+
+func parseLiteralTerm(tok string) (Term, error) {
+	end := 1
+	for end < len(tok) {
+		if tok[end] == '\\' {
+			end += 2
+			continue
+		}
+		if tok[end] == '"' {
+			break
+		}
+		end++
+	}
+	if end >= len(tok) {
+		return Term{}, fmt.Errorf("unterminated literal %q", tok)
+	}
+	lexical := unescapeLiteral(tok[1:end])
+	rest := tok[end+1:]
+	switch {
+	case strings.HasPrefix(rest, "@"):
+		return NewLangLiteral(lexical, rest[1:]), nil
+	case strings.HasPrefix(rest, "^^"):
+		dt := strings.TrimPrefix(rest, "^^")
+		dt = strings.TrimPrefix(dt, "<")
+		dt = strings.TrimSuffix(dt, ">")
+		return NewTypedLiteral(lexical, dt), nil
+	case rest == "":
+		return NewLiteral(lexical), nil
+	default:
+		return Term{}, fmt.Errorf("malformed literal suffix %q", rest)
+	}
+}
+
+var ntEscapes = strings.NewReplacer(
+	`\\`, `\`,
+	`\"`, `"`,
+	`\n`, "\n",
+	`\r`, "\r",
+	`\t`, "\t",
+)
+
+func unescapeLiteral(s string) string {
+	return ntEscapes.Replace(s)
+}
+
+func unescapeIRI(s string) string {
+	return ntEscapes.Replace(s)
+}
@@ -0,0 +1,51 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/crsdrw/radv/database"
+)
+
+func TestNTriplesLoadDumpRoundTrip(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob> .
+<http://example.org/alice> <http://example.org/name> "Alice"@en .
+`
+	n, err := LoadNTriples(ts, strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LoadNTriples: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("LoadNTriples loaded %d triples, want 2", n)
+	}
+
+	var buf bytes.Buffer
+	if err := DumpNTriples(ts, &buf); err != nil {
+		t.Fatalf("DumpNTriples: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<http://example.org/alice> <http://example.org/knows> <http://example.org/bob> .") {
+		t.Fatalf("dump missing the IRI triple, got:\n%s", out)
+	}
+	if !strings.Contains(out, `<http://example.org/alice> <http://example.org/name> "Alice"@en .`) {
+		t.Fatalf("dump missing the lang-tagged literal triple, got:\n%s", out)
+	}
+}
+
+func TestNTriplesLoadMalformedLineErrors(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob .`
+	if _, err := LoadNTriples(ts, strings.NewReader(doc)); err == nil {
+		t.Fatal("LoadNTriples succeeded on an unterminated IRI, want an error")
+	}
+}
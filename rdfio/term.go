@@ -0,0 +1,143 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+// Package rdfio streams triples and quads in and out of a
+// database.Triplestore using standard RDF serializations (Turtle,
+// N-Triples, N-Quads, JSON-LD).
+package rdfio
+
+import (
+	"encoding/gob"
+	"fmt"
+
+	"github.com/crsdrw/radv/database"
+)
+
+func init() {
+	// Term is stored in a Triplestore term's interface{} value; register
+	// it so database's default (gob-based) TermCodec can decode it back.
+	gob.Register(Term{})
+
+	// Do the same for database.RLPCodec: Term implements RLPTerm (see
+	// RLPFields below), so register how to decode those fields back
+	// into a Term, giving RLPCodec a real field-wise encoding for RDF
+	// terms instead of its generic gob fallback.
+	database.RegisterRLPTermDecoder(decodeRLPTerm)
+}
+
+// TermKind distinguishes the three kinds of RDF term.
+type TermKind int
+
+const (
+	// IRI is an absolute IRI reference, e.g. <http://example.org/thing>.
+	IRI TermKind = iota
+	// BlankNode is a blank node identifier, e.g. _:b0.
+	BlankNode
+	// Literal is a plain, language-tagged or typed literal.
+	Literal
+)
+
+// Term is an RDF term. Unlike the raw Go values Triplestore.Put accepts,
+// Term keeps IRIs, blank nodes and literal datatype/language information
+// distinct so marshal/unmarshal round-trip through import and export
+// without collapsing everything to a bare Go string.
+type Term struct {
+	Kind     TermKind
+	Value    string // IRI string, blank node label, or literal lexical form
+	Datatype string // literal datatype IRI; empty for plain or lang-tagged literals
+	Lang     string // literal language tag; empty unless Kind == Literal
+}
+
+// NewIRI returns an IRI term.
+func NewIRI(iri string) Term {
+	return Term{Kind: IRI, Value: iri}
+}
+
+// NewBlankNode returns a blank node term with the given label.
+func NewBlankNode(label string) Term {
+	return Term{Kind: BlankNode, Value: label}
+}
+
+// NewLiteral returns a plain (untyped, untagged) literal term.
+func NewLiteral(lexical string) Term {
+	return Term{Kind: Literal, Value: lexical}
+}
+
+// NewLangLiteral returns a language-tagged literal term.
+func NewLangLiteral(lexical, lang string) Term {
+	return Term{Kind: Literal, Value: lexical, Lang: lang}
+}
+
+// NewTypedLiteral returns a typed literal term.
+func NewTypedLiteral(lexical, datatype string) Term {
+	return Term{Kind: Literal, Value: lexical, Datatype: datatype}
+}
+
+// RLPFields implements database.RLPTerm, giving database.RLPCodec a
+// real field-wise encoding for Term instead of its generic gob fallback.
+func (t Term) RLPFields() [][]byte {
+	return [][]byte{
+		{byte(t.Kind)},
+		[]byte(t.Value),
+		[]byte(t.Datatype),
+		[]byte(t.Lang),
+	}
+}
+
+// decodeRLPTerm is the inverse of RLPFields, registered with
+// database.RegisterRLPTermDecoder so database.RLPCodec can reconstruct a
+// Term from the fields RLPFields produced.
+func decodeRLPTerm(fields [][]byte) (interface{}, error) {
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("rdfio: rlp: term has %d fields, want 4", len(fields))
+	}
+	if len(fields[0]) != 1 {
+		return nil, fmt.Errorf("rdfio: rlp: malformed term kind")
+	}
+	return Term{
+		Kind:     TermKind(fields[0][0]),
+		Value:    string(fields[1]),
+		Datatype: string(fields[2]),
+		Lang:     string(fields[3]),
+	}, nil
+}
+
+// String renders the term using N-Triples term syntax.
+func (t Term) String() string {
+	switch t.Kind {
+	case IRI:
+		return "<" + t.Value + ">"
+	case BlankNode:
+		return "_:" + t.Value
+	default:
+		lit := fmt.Sprintf("%q", t.Value)
+		switch {
+		case t.Lang != "":
+			return lit + "@" + t.Lang
+		case t.Datatype != "":
+			return lit + "^^<" + t.Datatype + ">"
+		default:
+			return lit
+		}
+	}
+}
+
+// Triple is a subject/predicate/object statement in the default graph.
+type Triple struct {
+	Subject   Term
+	Predicate Term
+	Object    Term
+}
+
+// Quad is a Triple plus its named graph. A zero-value Graph (Term{})
+// means the default graph.
+type Quad struct {
+	Triple
+	Graph Term
+}
+
+// IsDefaultGraph reports whether q belongs to the default graph.
+func (q Quad) IsDefaultGraph() bool {
+	return q.Graph == Term{}
+}
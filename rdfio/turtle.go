@@ -0,0 +1,352 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/crsdrw/radv/database"
+)
+
+// LoadTurtle reads a Turtle document from r and writes its triples into
+// ts. A relative IRI starting with "#" or "/" is appended to base (minus
+// any trailing "/"); any other relative IRI is kept as written, not
+// resolved against base. It supports the common
+// subset of Turtle used by most RDF dumps: @prefix/@base directives,
+// prefixed names, predicate-object lists (";") and object lists (","),
+// plain/lang/typed literals and blank nodes. It does not support
+// collections ("(...)") or anonymous blank node property lists
+// ("[...]"). It returns the number of triples written.
+func LoadTurtle(ts *database.Triplestore, r io.Reader, base string, opts ...BatchOption) (int64, error) {
+	o := newLoadOptions(opts)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	p := &turtleParser{
+		toks:     tokenizeTurtle(string(data)),
+		prefixes: map[string]string{},
+		base:     base,
+	}
+
+	batch := make([][3]interface{}, 0, o.batchSize)
+	var total int64
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := ts.PutAll(batch, o.batchSize)
+		total += n
+		batch = batch[:0]
+		return err
+	}
+
+	for p.more() {
+		tr, directive, err := p.statement()
+		if err != nil {
+			return total, fmt.Errorf("rdfio: turtle: %w", err)
+		}
+		if directive {
+			continue
+		}
+		for _, t := range tr {
+			batch = append(batch, [3]interface{}{t.Subject, t.Predicate, t.Object})
+			if len(batch) >= o.batchSize {
+				if err := flush(); err != nil {
+					return total, err
+				}
+			}
+		}
+	}
+	return total, flush()
+}
+
+type turtleParser struct {
+	toks     []string
+	pos      int
+	prefixes map[string]string
+	base     string
+	blankSeq int
+}
+
+func (p *turtleParser) more() bool { return p.pos < len(p.toks) }
+
+func (p *turtleParser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos]
+}
+
+func (p *turtleParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// statement parses one top-level Turtle statement: a directive or a
+// subject predicate-object-list terminated by ".".
+func (p *turtleParser) statement() ([]Triple, bool, error) {
+	switch p.peek() {
+	case "@prefix":
+		p.next()
+		name := strings.TrimSuffix(p.next(), ":")
+		iri, err := p.expectIRI()
+		if err != nil {
+			return nil, true, err
+		}
+		p.prefixes[name] = iri
+		if err := p.expect("."); err != nil {
+			return nil, true, err
+		}
+		return nil, true, nil
+	case "@base":
+		p.next()
+		iri, err := p.expectIRI()
+		if err != nil {
+			return nil, true, err
+		}
+		p.base = iri
+		if err := p.expect("."); err != nil {
+			return nil, true, err
+		}
+		return nil, true, nil
+	}
+
+	subj, err := p.term()
+	if err != nil {
+		return nil, false, err
+	}
+	triples, err := p.predicateObjectList(subj)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := p.expect("."); err != nil {
+		return nil, false, err
+	}
+	return triples, false, nil
+}
+
+func (p *turtleParser) predicateObjectList(subject Term) ([]Triple, error) {
+	var triples []Triple
+	for {
+		pred, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		objs, err := p.objectList()
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			triples = append(triples, Triple{Subject: subject, Predicate: pred, Object: o})
+		}
+		if p.peek() != ";" {
+			break
+		}
+		p.next()
+		if p.peek() == "." || p.peek() == "" {
+			break
+		}
+	}
+	return triples, nil
+}
+
+func (p *turtleParser) objectList() ([]Term, error) {
+	var objs []Term
+	for {
+		o, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	return objs, nil
+}
+
+// expect consumes tok if it's next, and errors out otherwise rather than
+// silently leaving the parser where it was, which would let the
+// following statement bleed into this one.
+func (p *turtleParser) expect(tok string) error {
+	if p.peek() != tok {
+		return fmt.Errorf("expected %q, got %q", tok, p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *turtleParser) expectIRI() (string, error) {
+	t, err := p.term()
+	if err != nil {
+		return "", err
+	}
+	if t.Kind != IRI {
+		return "", fmt.Errorf("expected IRI, got %v", t)
+	}
+	return t.Value, nil
+}
+
+func (p *turtleParser) term() (Term, error) {
+	tok := p.next()
+	if tok == "" {
+		return Term{}, fmt.Errorf("unexpected end of input")
+	}
+	switch {
+	case tok == "a":
+		return NewIRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#type"), nil
+	case strings.HasPrefix(tok, "<"):
+		return NewIRI(resolveIRI(p.base, unescapeIRI(tok[1:len(tok)-1]))), nil
+	case strings.HasPrefix(tok, "_:"):
+		return NewBlankNode(tok[2:]), nil
+	case strings.HasPrefix(tok, "\""):
+		return parseLiteralTurtle(tok, p.prefixes, p.base)
+	case strings.Contains(tok, ":"):
+		parts := strings.SplitN(tok, ":", 2)
+		iri, ok := p.prefixes[parts[0]]
+		if !ok {
+			return Term{}, fmt.Errorf("unknown prefix %q", parts[0])
+		}
+		return NewIRI(iri + parts[1]), nil
+	default:
+		return Term{}, fmt.Errorf("unrecognized term %q", tok)
+	}
+}
+
+func parseLiteralTurtle(tok string, prefixes map[string]string, base string) (Term, error) {
+	end := 1
+	for end < len(tok) {
+		if tok[end] == '\\' {
+			end += 2
+			continue
+		}
+		if tok[end] == '"' {
+			break
+		}
+		end++
+	}
+	lexical := unescapeLiteral(tok[1:end])
+	rest := tok[end+1:]
+	switch {
+	case strings.HasPrefix(rest, "@"):
+		return NewLangLiteral(lexical, rest[1:]), nil
+	case strings.HasPrefix(rest, "^^<"):
+		return NewTypedLiteral(lexical, resolveIRI(base, strings.TrimSuffix(strings.TrimPrefix(rest, "^^<"), ">"))), nil
+	case strings.HasPrefix(rest, "^^"):
+		parts := strings.SplitN(strings.TrimPrefix(rest, "^^"), ":", 2)
+		if len(parts) != 2 {
+			return Term{}, fmt.Errorf("malformed datatype %q", rest)
+		}
+		iri, ok := prefixes[parts[0]]
+		if !ok {
+			return Term{}, fmt.Errorf("unknown prefix %q", parts[0])
+		}
+		return NewTypedLiteral(lexical, iri+parts[1]), nil
+	default:
+		return NewLiteral(lexical), nil
+	}
+}
+
+func resolveIRI(base, iri string) string {
+	if base == "" || strings.Contains(iri, "://") {
+		return iri
+	}
+	if strings.HasPrefix(iri, "#") || strings.HasPrefix(iri, "/") {
+		return strings.TrimSuffix(base, "/") + iri
+	}
+	return iri
+}
+
+// tokenizeTurtle splits a Turtle document into whitespace-separated
+// tokens, treating <...>, "..." (with escapes) and the "." "," ";"
+// punctuation as their own tokens, and skipping "#" comments.
+func tokenizeTurtle(doc string) []string {
+	var toks []string
+	i, n := 0, len(doc)
+	for i < n {
+		switch {
+		case doc[i] == ' ' || doc[i] == '\t' || doc[i] == '\n' || doc[i] == '\r':
+			i++
+		case doc[i] == '#':
+			for i < n && doc[i] != '\n' {
+				i++
+			}
+		case doc[i] == '<':
+			end := strings.IndexByte(doc[i:], '>')
+			if end < 0 {
+				return toks
+			}
+			toks = append(toks, doc[i:i+end+1])
+			i += end + 1
+		case doc[i] == '"':
+			j := i + 1
+			for j < n {
+				if doc[j] == '\\' {
+					j += 2
+					continue
+				}
+				if doc[j] == '"' {
+					j++
+					break
+				}
+				j++
+			}
+			// trailing @lang or ^^datatype is tokenized greedily with the literal
+			if j < n && doc[j] == '@' {
+				start := j
+				j++
+				for j < n && !isTurtleDelim(doc[j]) {
+					j++
+				}
+				toks = append(toks, doc[i:start]+doc[start:j])
+				i = j
+				continue
+			}
+			if j+1 < n && doc[j] == '^' && doc[j+1] == '^' {
+				start := j
+				j += 2
+				if j < n && doc[j] == '<' {
+					end := strings.IndexByte(doc[j:], '>')
+					if end >= 0 {
+						j += end + 1
+					}
+				} else {
+					for j < n && !isTurtleDelim(doc[j]) {
+						j++
+					}
+				}
+				toks = append(toks, doc[i:start]+doc[start:j])
+				i = j
+				continue
+			}
+			toks = append(toks, doc[i:j])
+			i = j
+		case doc[i] == '.' || doc[i] == ',' || doc[i] == ';':
+			toks = append(toks, string(doc[i]))
+			i++
+		default:
+			start := i
+			for i < n && !isTurtleDelim(doc[i]) {
+				i++
+			}
+			toks = append(toks, doc[start:i])
+		}
+	}
+	return toks
+}
+
+func isTurtleDelim(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '.', ',', ';', '<', '"', '#':
+		return true
+	}
+	return false
+}
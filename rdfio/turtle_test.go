@@ -0,0 +1,40 @@
+// Licensed to the public under one or more agreements.
+// Crystal Construct Limited licenses this file to you under the MIT license.
+// See the LICENSE file in the project root for more information.
+
+package rdfio
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/crsdrw/radv/database"
+)
+
+func TestLoadTurtle(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob> .`
+	n, err := LoadTurtle(ts, strings.NewReader(doc), "", WithBatchSize(10))
+	if err != nil {
+		t.Fatalf("LoadTurtle: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("LoadTurtle loaded %d triples, want 1", n)
+	}
+}
+
+// TestLoadTurtleMissingTerminatorErrors checks that a statement missing
+// its terminating "." fails loudly instead of silently letting the next
+// statement bleed into it.
+func TestLoadTurtleMissingTerminatorErrors(t *testing.T) {
+	ts := database.New(database.WithDir(t.TempDir()))
+	defer ts.Close()
+
+	doc := `<http://example.org/alice> <http://example.org/knows> <http://example.org/bob>
+<http://example.org/bob> <http://example.org/knows> <http://example.org/carol> .`
+	if _, err := LoadTurtle(ts, strings.NewReader(doc), ""); err == nil {
+		t.Fatal("LoadTurtle succeeded on a statement missing its terminating \".\", want an error")
+	}
+}